@@ -0,0 +1,107 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ActionTimeoutError indicates that an action didn't finish within the timeout set by SetActionTimeout.
+// The FSM is left in the state the action was run from.
+type ActionTimeoutError struct {
+	State int
+	Input Input
+}
+
+func (err ActionTimeoutError) Error() string {
+	return fmt.Sprintf("action timed out.  (State: %v, Input: %v)", err.State, err.Input)
+}
+
+// SetActionTimeout limits how long each action may run.
+// Actions receive a context with the deadline applied, and Spin returns an ActionTimeoutError if it passes.
+// Go can't stop a running function, so an action which ignores its context still runs to completion
+// before Spin returns; it just can't commit its transition.
+// A duration of zero or less removes the limit.
+// This method is thread-safe.
+func (f *FSM) SetActionTimeout(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.actionTimeout = d
+}
+
+// SetRecover makes Spin recover from panicking actions, instead of letting the panic propagate.
+// fn converts the recovered value into an error, which Spin returns wrapped in an ActionError,
+// leaving the FSM in the state the action was run from.
+// If fn returns nil the transition completes as if the action had returned NO_INPUT.
+// A nil fn restores the default of propagating panics.
+// This method is thread-safe.
+func (f *FSM) SetRecover(fn func(r interface{}) error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.recoverFn = fn
+}
+
+// runAction runs the action of an Outcome for the current state, wrapped in the registered middleware,
+// applying the action timeout.
+// The caller must hold the lock.
+func (f *FSM) runAction(ctx context.Context, in Input, do Outcome) (context.Context, Input, error) {
+	if f.metrics != nil {
+		defer func(state int, start time.Time) {
+			f.metrics.ActionDuration(state, in, time.Since(start))
+		}(f.current, time.Now())
+	}
+
+	if do.ActionE == nil && len(f.middleware) == 0 && f.recoverFn == nil && f.actionTimeout <= 0 {
+		// Nothing to wrap the action in, so it is called as is rather than adapted into an ActionE.
+		action := do.Action
+		if action == nil {
+			action = NO_ACTION
+		}
+		ctx, out := action(ctx)
+		return ctx, out, nil
+	}
+
+	action := f.withMiddleware(do.run())
+	if f.recoverFn != nil {
+		action = recovering(action, f.recoverFn)
+	}
+
+	if f.actionTimeout <= 0 {
+		return action(ctx)
+	}
+
+	actx, cancel := context.WithTimeout(ctx, f.actionTimeout)
+	defer cancel()
+
+	next, out, err := action(actx)
+	if actx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return ctx, out, ActionTimeoutError{f.current, in}
+	}
+
+	// Keep any values the action added, but not the deadline.
+	return rebasedContext{ctx, next}, out, err
+}
+
+// recovering wraps an action so a panic is converted into an error by fn.
+func recovering(action ActionE, fn func(r interface{}) error) ActionE {
+	return func(ctx context.Context) (next context.Context, out Input, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				next, out, err = ctx, NO_INPUT, fn(r)
+			}
+		}()
+		return action(ctx)
+	}
+}
+
+// rebasedContext takes its values from one context, and its deadline and cancellation from another.
+type rebasedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c rebasedContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
@@ -0,0 +1,49 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { panic("boom") }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	// Without a handler the panic propagates, and the lock is released.
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Panic didn't propagate without a recover handler.")
+			}
+		}()
+		fsm.Spin(ctx, test_input_1)
+	}()
+
+	fsm.SetRecover(func(r interface{}) error { return fmt.Errorf("recovered: %v", r) })
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch err.(type) {
+	case ActionError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("FSM moved after panicking action. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+}
@@ -0,0 +1,377 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NoPathError indicates that no sequence of inputs leads from one state to another.
+type NoPathError struct {
+	From int
+	To   int
+}
+
+func (err NoPathError) Error() string {
+	return fmt.Sprintf("no path between states.  (From: %v, To: %v)", err.From, err.To)
+}
+
+// NameError reports a gap between the names set by SetLogger and the FSM definition.
+// Kind is either "state" or "input". Unused is false for a state or input without a name,
+// and true for a name given to a state or input which isn't part of the definition.
+type NameError struct {
+	Kind   string
+	Index  int
+	Unused bool
+}
+
+func (err NameError) Error() string {
+	if err.Unused {
+		return fmt.Sprintf("name given to undefined %s.  (%v: %v)", err.Kind, err.Kind, err.Index)
+	}
+	return fmt.Sprintf("%s has no name.  (%v: %v)", err.Kind, err.Kind, err.Index)
+}
+
+// StateCount returns the number of states in the FSM definition.
+// This method is thread-safe.
+func (f *FSM) StateCount() int {
+	f.RLock()
+	defer f.RUnlock()
+
+	return len(f.states)
+}
+
+// DefinedInputs returns every input any state has an Outcome for, without duplicates and sorted ascending.
+// Sentinel keys such as ANY_INPUT are included as they appear in the definition.
+// This method is thread-safe.
+func (f *FSM) DefinedInputs() []Input {
+	f.RLock()
+	defer f.RUnlock()
+
+	inputs := map[Input]Outcome{}
+	for _, s := range f.states {
+		for in, do := range s.Outcomes {
+			inputs[in] = do
+		}
+	}
+	return sortedInputs(inputs)
+}
+
+// WalkTransitions calls fn for every transition of the FSM definition, sorted by state and then by input.
+// fn is called without holding the lock, so it may safely use the FSM.
+// The Outcome carries its Meta labels, which fn must not modify.
+// This method is thread-safe.
+func (f *FSM) WalkTransitions(fn func(from int, in Input, out Outcome)) {
+	type transition struct {
+		from int
+		in   Input
+		out  Outcome
+	}
+
+	f.RLock()
+	var transitions []transition
+	for _, index := range f.sortedStates() {
+		s := f.states[index]
+		for _, in := range sortedInputs(s.Outcomes) {
+			transitions = append(transitions, transition{index, in, s.Outcomes[in]})
+		}
+	}
+	f.RUnlock()
+
+	for _, t := range transitions {
+		fn(t.from, t.in, t.out)
+	}
+}
+
+// UnreachableStates returns the indices of all states which can never be reached from the initial state,
+// following the target State of every Outcome, including the default outcome set by SetDefaultOutcome.
+// The result is sorted ascending, and is empty if every state is reachable.
+// Targets picked at spin time by a Chooser aren't known, so states only reached through a Chooser are reported too.
+// The targets of weighted Outcomes are all followed.
+// This method is thread-safe.
+func (f *FSM) UnreachableStates() []int {
+	f.RLock()
+	defer f.RUnlock()
+
+	reached := f.reachableFrom(f.initial)
+
+	unreachable := []int{}
+	for index := range f.states {
+		if !reached[index] {
+			unreachable = append(unreachable, index)
+		}
+	}
+	sort.Ints(unreachable)
+	return unreachable
+}
+
+// ReachableStatesBFS returns the indices of all states reachable from the initial state,
+// in the order they are first met by a breadth-first traversal following the target State of every Outcome,
+// with the Outcomes of each state taken in ascending input order. The initial state comes first.
+// States which can't be reached are left out: see UnreachableStates.
+// This method is thread-safe.
+func (f *FSM) ReachableStatesBFS() []int {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.breadthFirst(f.initial)
+}
+
+// StatesThatCannotReach returns the indices of all states from which no sequence of transitions leads to goal,
+// sorted ascending.
+// Will return an ImpossibleStateError if goal isn't part of the FSM definition.
+// This method is thread-safe.
+func (f *FSM) StatesThatCannotReach(goal int) ([]int, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	if _, ok := f.states[goal]; !ok {
+		return nil, ImpossibleStateError(goal)
+	}
+
+	// Walk the transitions backwards from the goal.
+	sources := map[int][]int{}
+	for index, s := range f.states {
+		for _, do := range s.Outcomes {
+			for _, target := range do.targets() {
+				sources[target] = append(sources[target], index)
+			}
+		}
+	}
+
+	reaches := map[int]bool{goal: true}
+	queue := []int{goal}
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+
+		for _, index := range sources[target] {
+			if !reaches[index] {
+				reaches[index] = true
+				queue = append(queue, index)
+			}
+		}
+	}
+
+	stuck := []int{}
+	for _, index := range f.sortedStates() {
+		if !reaches[index] {
+			stuck = append(stuck, index)
+		}
+	}
+	return stuck, nil
+}
+
+// DeadTransitions returns the transitions which can never fire, as (state, input) pairs sorted by state and then by input:
+// those of states which can't be reached from the initial state,
+// CHILD_DONE Outcomes of states without a Child machine to complete,
+// and ACTION_FAILED Outcomes of states other than the error state set by SetErrorState.
+// Each one is also logged as a warning.
+// This method is thread-safe.
+func (f *FSM) DeadTransitions() [][2]int {
+	f.RLock()
+	defer f.RUnlock()
+
+	reached := f.reachableFrom(f.initial)
+
+	dead := [][2]int{}
+	for _, index := range f.sortedStates() {
+		s := f.states[index]
+		for _, in := range sortedInputs(s.Outcomes) {
+			isErrorState := f.errorState != nil && *f.errorState == index
+			if reached[index] && (in != CHILD_DONE || s.Child != nil) && (in != ACTION_FAILED || isErrorState) {
+				continue
+			}
+			if f.logs(logrus.WarnLevel) {
+				f.logEntry(index, in).Warnf("FSM: dead transition in state [%d][%s] on input [%d][%s]", index, f.getStateName(index), in, f.getInputName(in))
+			}
+			dead = append(dead, [2]int{index, int(in)})
+		}
+	}
+	return dead
+}
+
+// PathTo returns a shortest sequence of inputs which moves the FSM from its current state to target,
+// which is empty if the FSM is already there.
+// Only the target State of each Outcome is followed: inputs chained by actions, Guards and Choosers aren't taken
+// into account, and ANY_INPUT, CHILD_DONE and ACTION_FAILED Outcomes are skipped, since they can't be passed to Spin.
+// Among paths of the same length, the one with the lowest inputs is returned.
+// Will return an ImpossibleStateError if target isn't part of the FSM definition,
+// or a NoPathError if it can't be reached.
+// This method is thread-safe.
+func (f *FSM) PathTo(target int) ([]Input, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	if _, ok := f.states[target]; !ok {
+		return nil, ImpossibleStateError(target)
+	}
+
+	type step struct {
+		from int
+		in   Input
+	}
+	prev := map[int]step{}
+	seen := map[int]bool{f.current: true}
+	queue := []int{f.current}
+	for len(queue) > 0 && !seen[target] {
+		index := queue[0]
+		queue = queue[1:]
+
+		s := f.states[index]
+		for _, in := range sortedInputs(s.Outcomes) {
+			next := s.Outcomes[in].target(index)
+			if in == ANY_INPUT || in == CHILD_DONE || in == ACTION_FAILED || seen[next] || !hasState(f.states, next) {
+				continue
+			}
+			seen[next] = true
+			prev[next] = step{index, in}
+			queue = append(queue, next)
+		}
+	}
+
+	if !seen[target] {
+		return nil, NoPathError{f.current, target}
+	}
+
+	path := []Input{}
+	for index := target; index != f.current; index = prev[index].from {
+		path = append([]Input{prev[index].in}, path...)
+	}
+	return path, nil
+}
+
+// TerminalStates returns the indices of all states without any Outcomes, sorted ascending.
+// This method is thread-safe.
+func (f *FSM) TerminalStates() []int {
+	f.RLock()
+	defer f.RUnlock()
+
+	terminal := []int{}
+	for index, s := range f.states {
+		if len(s.Outcomes) == 0 {
+			terminal = append(terminal, index)
+		}
+	}
+	sort.Ints(terminal)
+	return terminal
+}
+
+// IsTerminal reports whether the current state has no Outcomes, so no input can move the FSM any further.
+// This method is thread-safe.
+func (f *FSM) IsTerminal() bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return len(f.states[f.current].Outcomes) == 0
+}
+
+// reachableFrom returns the set of defined states reachable from start, including start itself.
+func (f *FSM) reachableFrom(start int) map[int]bool {
+	reached := map[int]bool{}
+	for _, index := range f.breadthFirst(start) {
+		reached[index] = true
+	}
+	return reached
+}
+
+// breadthFirst returns the defined states reachable from start, including start itself,
+// in the order a breadth-first traversal following Outcomes in ascending input order meets them.
+// The targets of a weighted Outcome are followed in the order they are listed.
+// The error state set by SetErrorState is reached from any state with an Outcome, after its Outcomes.
+// The targets of the default outcome set by SetDefaultOutcome are reached from any state, last.
+func (f *FSM) breadthFirst(start int) []int {
+	if _, ok := f.states[start]; !ok {
+		return []int{}
+	}
+
+	reached := map[int]bool{start: true}
+	order := []int{start}
+	for next := 0; next < len(order); next++ {
+		s := f.states[order[next]]
+		for _, in := range sortedInputs(s.Outcomes) {
+			for _, target := range s.Outcomes[in].targets() {
+				if _, ok := f.states[target]; !ok || reached[target] {
+					continue
+				}
+				reached[target] = true
+				order = append(order, target)
+			}
+		}
+		if es := f.errorState; es != nil && len(s.Outcomes) > 0 && hasState(f.states, *es) && !reached[*es] {
+			reached[*es] = true
+			order = append(order, *es)
+		}
+		if f.fallback != nil {
+			for _, target := range f.fallback.targets() {
+				if _, ok := f.states[target]; !ok || reached[target] {
+					continue
+				}
+				reached[target] = true
+				order = append(order, target)
+			}
+		}
+	}
+	return order
+}
+
+// ValidateNames checks the names set by SetLogger or SetNamedTypes against the FSM definition.
+// It returns a NameError for every state without a name, every input used by an Outcome without a name,
+// and every name in the maps given to SetLogger for a state or input which isn't used,
+// or nil if the naming is complete. Names computed by SetNamedTypes functions are only checked for being empty.
+// The ANY_INPUT, CHILD_DONE and ACTION_FAILED sentinels don't need names.
+// States are reported before inputs, each sorted ascending.
+// This method is thread-safe.
+func (f *FSM) ValidateNames() []error {
+	f.RLock()
+	defer f.RUnlock()
+
+	var errs []error
+
+	for _, index := range f.sortedStates() {
+		if f.getStateName(index) == "" {
+			errs = append(errs, NameError{"state", index, false})
+		}
+	}
+	var named []int
+	for index := range f.stateNames {
+		if f.stateNamer == nil {
+			named = append(named, index)
+		}
+	}
+	sort.Ints(named)
+	for _, index := range named {
+		if _, ok := f.states[index]; !ok {
+			errs = append(errs, NameError{"state", index, true})
+		}
+	}
+
+	used := map[Input]Outcome{}
+	for _, s := range f.states {
+		for in, do := range s.Outcomes {
+			if in != ANY_INPUT && in != CHILD_DONE && in != ACTION_FAILED {
+				used[in] = do
+			}
+		}
+	}
+	for _, in := range sortedInputs(used) {
+		if f.getInputName(in) == "" {
+			errs = append(errs, NameError{"input", int(in), false})
+		}
+	}
+	named = named[:0]
+	for in := range f.inputNames {
+		if f.inputNamer == nil {
+			named = append(named, int(in))
+		}
+	}
+	sort.Ints(named)
+	for _, in := range named {
+		if _, ok := used[Input(in)]; !ok {
+			errs = append(errs, NameError{"input", in, true})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,371 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUnreachableStates(t *testing.T) {
+	// State 3 loops back to 1 but nothing ever goes to it.
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	unreachable := fsm.UnreachableStates()
+	if len(unreachable) != 1 || unreachable[0] != test_state_3 {
+		t.Errorf("Wrong unreachable states. (Expected: %v, Got: %v)", []int{test_state_3}, unreachable)
+	}
+
+	// The default outcome reaches state 3 from anywhere.
+	if err := fsm.SetDefaultOutcome(Outcome{State: test_state_3, Action: NO_ACTION}); err != nil {
+		t.Fatal("Failed to set default outcome: ", err)
+	}
+	if unreachable := fsm.UnreachableStates(); len(unreachable) != 0 {
+		t.Errorf("Wrong unreachable states. (Expected: none, Got: %v)", unreachable)
+	}
+	if err := fsm.Validate(); err != nil {
+		t.Errorf("FSM reached through its default outcome invalid: %v", err)
+	}
+	if dead := fsm.DeadTransitions(); len(dead) != 0 {
+		t.Errorf("Wrong dead transitions. (Expected: none, Got: %v)", dead)
+	}
+}
+
+func TestTerminalStates(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	terminal := fsm.TerminalStates()
+	if len(terminal) != 2 || terminal[0] != test_state_2 || terminal[1] != test_state_3 {
+		t.Errorf("Wrong terminal states. (Expected: %v, Got: %v)", []int{test_state_2, test_state_3}, terminal)
+	}
+
+	if fsm.IsTerminal() {
+		t.Errorf("Initial state reported as terminal.")
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if !fsm.IsTerminal() {
+		t.Errorf("Terminal state not reported as terminal.")
+	}
+}
+
+func TestWalkTransitions(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION, Meta: map[string]string{"doc": "start"}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	// Define out of order to check the walk is sorted.
+	fsm, err := Define(state2, state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var got [][3]int
+	var docs []string
+	fsm.WalkTransitions(func(from int, in Input, out Outcome) {
+		got = append(got, [3]int{from, int(in), out.State})
+		docs = append(docs, out.Meta["doc"])
+	})
+	if docs[0] != "start" || docs[1] != "" {
+		t.Errorf("Wrong transition metadata. (Expected: %v, Got: %v)", []string{"start", "", ""}, docs)
+	}
+
+	expected := [][3]int{
+		{test_state_1, test_input_1, test_state_2},
+		{test_state_1, test_input_2, test_state_1},
+		{test_state_2, test_input_1, test_state_1},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong transitions. (Expected: %v, Got: %v)", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Wrong transitions. (Expected: %v, Got: %v)", expected, got)
+		}
+	}
+}
+
+func TestReachableStatesBFS(t *testing.T) {
+	// The initial state is listed last, and state 4 is never reached.
+	fsm, err := DefineWithStart(3,
+		State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_1}}},
+		State{Index: test_state_2, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_1}}},
+		State{Index: test_state_3, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_2}}},
+		State{Index: 3, Outcomes: map[Input]Outcome{test_input_2: {State: test_state_2}, test_input_1: {State: test_state_3}}},
+		State{Index: 4, Outcomes: map[Input]Outcome{test_input_1: {State: 3}}},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	expected := []int{3, test_state_3, test_state_2, test_state_1}
+	order := fsm.ReachableStatesBFS()
+	if len(order) != len(expected) {
+		t.Fatalf("Wrong reachable states. (Expected: %v, Got: %v)", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Wrong reachable states. (Expected: %v, Got: %v)", expected, order)
+			break
+		}
+	}
+}
+
+func TestDeadTransitions(t *testing.T) {
+	// State 3 is never reached, and state 1 has no child to complete.
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			CHILD_DONE:   Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	expected := [][2]int{
+		{test_state_1, int(CHILD_DONE)},
+		{test_state_3, test_input_1},
+		{test_state_3, test_input_2},
+	}
+	dead := fsm.DeadTransitions()
+	if len(dead) != len(expected) {
+		t.Fatalf("Wrong dead transitions. (Expected: %v, Got: %v)", expected, dead)
+	}
+	for i := range expected {
+		if dead[i] != expected[i] {
+			t.Errorf("Wrong dead transitions. (Expected: %v, Got: %v)", expected, dead)
+			break
+		}
+	}
+}
+
+func TestPathTo(t *testing.T) {
+	ctx := context.Background()
+
+	// A long way round through state 2, and a shortcut through a wildcard that PathTo can't use.
+	fsm, err := Define(
+		State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_2}, ANY_INPUT: {State: 3}}},
+		State{Index: test_state_2, Outcomes: map[Input]Outcome{test_input_2: {State: test_state_3}, test_input_3: {State: test_state_1}}},
+		State{Index: test_state_3, Outcomes: map[Input]Outcome{test_input_3: {State: 3}}},
+		State{Index: 3},
+		State{Index: 4},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	path, err := fsm.PathTo(3)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []Input{test_input_1, test_input_2, test_input_3}
+	if len(path) != len(expected) || path[0] != expected[0] || path[1] != expected[1] || path[2] != expected[2] {
+		t.Fatalf("Wrong path. (Expected: %v, Got: %v)", expected, path)
+	}
+	if _, n, err := fsm.SpinAll(ctx, path...); err != nil || n != len(path) || fsm.Current() != 3 {
+		t.Errorf("Path didn't lead to target. (Expected: %v, Got: %v)", 3, fsm.Current())
+	}
+
+	if path, err := fsm.PathTo(3); err != nil || len(path) != 0 {
+		t.Errorf("Wrong path to current state. (Expected: %v, Got: %v)", []Input{}, path)
+	}
+
+	_, err = fsm.PathTo(4)
+	switch err.(type) {
+	case NoPathError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	_, err = fsm.PathTo(7)
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestStatesThatCannotReach(t *testing.T) {
+	// 1 -> 2 -> 3, and 3 only loops on itself.
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	stuck, err := fsm.StatesThatCannotReach(test_state_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(stuck) != 1 || stuck[0] != test_state_3 {
+		t.Errorf("Wrong stuck states. (Expected: %v, Got: %v)", []int{test_state_3}, stuck)
+	}
+
+	_, err = fsm.StatesThatCannotReach(7)
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestDefinitionSize(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_3: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if fsm.StateCount() != 2 {
+		t.Errorf("Wrong state count. (Expected: %v, Got: %v)", 2, fsm.StateCount())
+	}
+	inputs := fsm.DefinedInputs()
+	if len(inputs) != 2 || inputs[0] != test_input_1 || inputs[1] != test_input_3 {
+		t.Errorf("Wrong defined inputs. (Expected: %v, Got: %v)", []Input{test_input_1, test_input_3}, inputs)
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			ANY_INPUT:    Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	fsm.SetLogger(nil, map[int]string{test_state_1: "one", test_state_2: "two"}, map[Input]string{test_input_1: "in1", test_input_2: "in2"})
+	if errs := fsm.ValidateNames(); len(errs) != 0 {
+		t.Errorf("Complete naming reported errors: %v", errs)
+	}
+
+	fsm.SetLogger(nil, map[int]string{test_state_1: "one", 7: "seven"}, map[Input]string{test_input_1: "in1", test_input_3: "in3"})
+	expected := []NameError{
+		{"state", test_state_2, false},
+		{"state", 7, true},
+		{"input", int(test_input_2), false},
+		{"input", int(test_input_3), true},
+	}
+	errs := fsm.ValidateNames()
+	if len(errs) != len(expected) {
+		t.Fatalf("Wrong number of name errors. (Expected: %v, Got: %v)", expected, errs)
+	}
+	for i, err := range errs {
+		if err != expected[i] {
+			t.Errorf("Wrong name error. (Expected: %v, Got: %v)", expected[i], err)
+		}
+	}
+}
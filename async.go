@@ -0,0 +1,117 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+)
+
+// ASYNC_QUEUE is the number of inputs an AsyncFSM buffers before Send blocks.
+const ASYNC_QUEUE = 64
+
+// AsyncClosedError indicates that an input was sent to an AsyncFSM which has been closed.
+type AsyncClosedError struct{}
+
+func (err AsyncClosedError) Error() string {
+	return "async FSM is closed"
+}
+
+// AsyncResult is the outcome of spinning an input sent to an AsyncFSM.
+type AsyncResult struct {
+	Context context.Context
+	Err     error
+}
+
+// AsyncFSM spins an FSM from a single worker goroutine, consuming inputs from a queue.
+// Senders don't contend on the FSM lock: they enqueue an input and receive its result later.
+type AsyncFSM struct {
+	fsm    *FSM
+	mu     sync.RWMutex
+	closed bool
+	queue  chan asyncRequest
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+type asyncRequest struct {
+	ctx    context.Context
+	in     Input
+	result chan AsyncResult
+}
+
+// Async starts a worker goroutine which spins the FSM with the inputs sent to the returned AsyncFSM, in order.
+// The FSM can still be used directly, e.g. to read its current state.
+// Close must be called to stop the worker.
+func (f *FSM) Async() *AsyncFSM {
+	a := &AsyncFSM{
+		fsm:   f,
+		queue: make(chan asyncRequest, ASYNC_QUEUE),
+		quit:  make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+// Send enqueues an input and returns a channel which receives the result of spinning it.
+// Send only blocks while the queue is full, and gives up once ctx is done, reporting ctx.Err() as the result.
+// The context is also passed to Spin, so it should outlive the wait for the result.
+// The result is an AsyncClosedError if the AsyncFSM is closed before the input is spun.
+// This method is thread-safe.
+func (a *AsyncFSM) Send(ctx context.Context, in Input) <-chan AsyncResult {
+	result := make(chan AsyncResult, 1)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		result <- AsyncResult{ctx, AsyncClosedError{}}
+		return result
+	}
+	select {
+	case a.queue <- asyncRequest{ctx, in, result}:
+	case <-ctx.Done():
+		result <- AsyncResult{ctx, ctx.Err()}
+	}
+	return result
+}
+
+// Close stops the worker once the input it is spinning is done, and waits for it.
+// Inputs still queued receive an AsyncClosedError.
+// Calling Close more than once is a no-op.
+// This method is thread-safe.
+func (a *AsyncFSM) Close() {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	close(a.quit)
+	a.wg.Wait()
+
+	for {
+		select {
+		case r := <-a.queue:
+			r.result <- AsyncResult{r.ctx, AsyncClosedError{}}
+		default:
+			return
+		}
+	}
+}
+
+// run is the worker loop spinning queued inputs.
+func (a *AsyncFSM) run() {
+	defer a.wg.Done()
+
+	for {
+		select {
+		case <-a.quit:
+			return
+		case r := <-a.queue:
+			ctx, err := a.fsm.Spin(r.ctx, r.in)
+			r.result <- AsyncResult{ctx, err}
+		}
+	}
+}
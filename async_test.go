@@ -0,0 +1,64 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// Test that inputs sent from many goroutines are all spun. Run with -race.
+func TestAsync(t *testing.T) {
+	ctx := context.Background()
+
+	var count int
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: func(ctx context.Context) (context.Context, Input) {
+				count++
+				return ctx, NO_INPUT
+			}},
+		},
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	a := fsm.Async()
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r := <-a.Send(ctx, test_input_1); r.Err != nil {
+				t.Error(r.Err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	r := <-a.Send(ctx, test_input_2)
+	switch r.Err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", r.Err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", r.Err)
+	}
+
+	a.Close()
+	a.Close()
+	if count != 10 {
+		t.Errorf("Wrong number of actions run. (Expected: %v, Got: %v)", 10, count)
+	}
+
+	r = <-a.Send(ctx, test_input_1)
+	switch r.Err.(type) {
+	case AsyncClosedError:
+		t.Logf("FSM corrently returned error: %v", r.Err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", r.Err)
+	}
+}
@@ -0,0 +1,57 @@
+package fsm
+
+import "github.com/sirupsen/logrus"
+
+// atomicSnapshot is the part of the FSM an atomic chain restores when it fails.
+type atomicSnapshot struct {
+	current int
+	undo    []int
+}
+
+// SetAtomicChains makes every Spin all or nothing: if any hop of an action chain fails,
+// the FSM is rolled back to the state it was in before the Spin, along with the states Undo can return to.
+// Transitions are only published to subscribers, the history and metrics once the whole chain has succeeded,
+// and the Done channel is only closed then, so nothing outside the FSM sees a chain which was rolled back.
+// The side effects of actions, hooks, OnBeforeStateChange and OnFirstVisit callbacks are not rolled back,
+// and the timeout of the state rolled back to is restarted.
+// A failed action routed to the error state under ErrorRoutingReturn isn't rolled back, since it was handled.
+// This method is thread-safe.
+func (f *FSM) SetAtomicChains(b bool) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.atomic = b
+}
+
+// beginAtomic starts holding back transitions, and returns what to restore if the chain fails.
+// The caller must hold the lock.
+func (f *FSM) beginAtomic() atomicSnapshot {
+	f.pending = []Transition{}
+	return atomicSnapshot{f.current, append([]int(nil), f.undo...)}
+}
+
+// endAtomic rolls the FSM back to saved if the chain failed, or publishes the transitions held back otherwise.
+// The caller must hold the lock.
+func (f *FSM) endAtomic(saved atomicSnapshot, err *error, routed *error) {
+	pending := f.pending
+	f.pending = nil
+
+	if *err != nil && *routed == nil {
+		if f.current != saved.current {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, NO_INPUT).Tracef("FSM: chain failed, roll back to state [%d][%s]", saved.current, f.getStateName(saved.current))
+			}
+			f.current = saved.current
+			f.armTimeout()
+		}
+		f.undo = saved.undo
+		return
+	}
+
+	for _, t := range pending {
+		f.notify(t)
+		if f.final[t.To] {
+			f.closeDone()
+		}
+	}
+}
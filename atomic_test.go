@@ -0,0 +1,88 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAtomicChains(t *testing.T) {
+	ctx := context.Background()
+
+	failure := errors.New("boom")
+	fail := true
+
+	// 1 -> 2 -> 3, where the second hop may fail.
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, ActionE: func(ctx context.Context) (context.Context, Input, error) {
+				if fail {
+					return ctx, NO_INPUT, failure
+				}
+				return ctx, NO_INPUT, nil
+			}},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetAtomicChains(true)
+	fsm.EnableHistory(10)
+	fsm.EnableUndo(10)
+	fsm.SetFinalStates(test_state_2)
+	done := fsm.Done()
+	ch := fsm.Subscribe()
+
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch err.(type) {
+	case ActionError:
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Fatalf("Wrong error type: %T (%v)", err, err)
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Chain not rolled back. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+	if fsm.LastChanged() {
+		t.Error("LastChanged true after a rolled back chain")
+	}
+	if len(fsm.History()) != 0 || len(ch) != 0 {
+		t.Errorf("Rolled back transitions were published: %v", fsm.History())
+	}
+	select {
+	case <-done:
+		t.Error("Done closed by a rolled back chain")
+	default:
+	}
+	if err := fsm.Undo(); err == nil {
+		t.Error("Undo possible after a rolled back chain")
+	}
+
+	fail = false
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+	assertHistory(t, fsm.History(), []Transition{
+		{From: test_state_1, To: test_state_2, Input: test_input_1},
+		{From: test_state_2, To: test_state_3, Input: test_input_2},
+	})
+	if len(ch) != 2 {
+		t.Errorf("Wrong number of published transitions. (Expected: 2, Got: %v)", len(ch))
+	}
+	select {
+	case <-done:
+	default:
+		t.Error("Done not closed by a chain through a final state")
+	}
+}
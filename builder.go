@@ -0,0 +1,124 @@
+package fsm
+
+import (
+	"fmt"
+)
+
+// A Builder constructs an FSM one state and transition at a time,
+// as an alternative to writing out State and Outcome literals.
+//
+//	fsm, err := NewBuilder().
+//		AddState(Idle).
+//			On(Start).Go(Running).Do(startAction).
+//		AddState(Running).
+//			On(Stop).Go(Idle).
+//		Build()
+//
+// The first state added is the initial state.
+type Builder struct {
+	states []State
+	strict bool
+	err    error
+}
+
+// A TransitionBuilder configures the Outcome of a single input.
+// It embeds the Builder, so further states and transitions can be chained directly.
+type TransitionBuilder struct {
+	*Builder
+	state int
+	input Input
+}
+
+// DuplicateTransitionError indicates that a strict Builder was given two transitions for the same state and input.
+type DuplicateTransitionError struct {
+	State int
+	Input Input
+}
+
+func (err DuplicateTransitionError) Error() string {
+	return fmt.Sprintf("attempt to define duplicate transition.  (State: %v, Input: %v)", err.State, err.Input)
+}
+
+// NewBuilder returns an empty Builder.
+// Calling On twice with the same input for a state replaces the earlier transition, like assigning to a map would.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Strict makes Build return a DuplicateTransitionError if On is called twice with the same input for a state,
+// instead of silently keeping the last transition.
+func (b *Builder) Strict() *Builder {
+	b.strict = true
+	return b
+}
+
+// AddState adds a new state to the machine, and makes it the target of subsequent calls to On.
+// Adding the same index twice makes Build return a ClashingStateError.
+func (b *Builder) AddState(index int) *Builder {
+	for _, s := range b.states {
+		if s.Index == index {
+			b.fail(ClashingStateError(index))
+			return b
+		}
+	}
+
+	b.states = append(b.states, State{Index: index, Outcomes: map[Input]Outcome{}})
+	return b
+}
+
+// On adds a transition on the given input to the most recently added state.
+// Until Go is called the transition stays in the same state, and until Do is called it runs NO_ACTION.
+func (b *Builder) On(in Input) *TransitionBuilder {
+	if len(b.states) == 0 {
+		b.fail(fmt.Errorf("builder: transition on input %v defined before any state", in))
+		return &TransitionBuilder{Builder: b, input: in}
+	}
+
+	s := b.states[len(b.states)-1]
+	if _, ok := s.Outcomes[in]; ok && b.strict {
+		b.fail(DuplicateTransitionError{s.Index, in})
+	}
+	s.Outcomes[in] = Outcome{State: s.Index, Action: NO_ACTION}
+	return &TransitionBuilder{Builder: b, state: len(b.states) - 1, input: in}
+}
+
+// Go sets the state the transition moves to.
+func (t *TransitionBuilder) Go(state int) *TransitionBuilder {
+	t.update(func(o *Outcome) { o.State = state })
+	return t
+}
+
+// Do sets the action the transition performs.
+func (t *TransitionBuilder) Do(action Action) *TransitionBuilder {
+	t.update(func(o *Outcome) { o.Action = action })
+	return t
+}
+
+// Build defines the FSM from everything added so far.
+// Returns the first error encountered while building, a ClashingStateError for duplicate states,
+// an UndefinedTargetError for transitions to states which were never added,
+// or an EmptyDefinitionError if no state was added.
+func (b *Builder) Build() (*FSM, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return Define(b.states...)
+}
+
+func (t *TransitionBuilder) update(fn func(*Outcome)) {
+	if len(t.states) == 0 {
+		return
+	}
+
+	s := t.states[t.state]
+	o := s.Outcomes[t.input]
+	fn(&o)
+	s.Outcomes[t.input] = o
+}
+
+// fail records the first error encountered while building.
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
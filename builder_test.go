@@ -0,0 +1,90 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	ctx := context.Background()
+
+	hit := false
+	fsm, err := NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_2).
+		On(test_input_2).
+		AddState(test_state_2).
+		On(test_input_1).Go(test_state_1).Do(func(ctx context.Context) (context.Context, Input) { hit = true; return ctx, NO_INPUT }).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build FSM: ", err)
+	}
+
+	t.Log("2: 1 -> 1")
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+
+	t.Log("1: 1 -> 2")
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	t.Log("1: 2 -> 1")
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	if !hit {
+		t.Errorf("Didn't hit action.")
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := NewBuilder().
+		AddState(test_state_1).
+		AddState(test_state_1).
+		Build()
+	switch err.(type) {
+	case ClashingStateError:
+		t.Logf("Builder corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("Builder returned wrong error type: %T", err)
+	}
+
+	_, err = NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_3).
+		Build()
+	switch err.(type) {
+	case UndefinedTargetError:
+		t.Logf("Builder corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("Builder returned wrong error type: %T", err)
+	}
+}
+
+func TestBuilderStrict(t *testing.T) {
+	// The lenient builder keeps the last transition.
+	fsm, err := NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_2).
+		On(test_input_1).Go(test_state_1).
+		AddState(test_state_2).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build FSM: ", err)
+	}
+	assertState(t, context.Background(), fsm, test_input_1, test_state_1)
+
+	_, err = NewBuilder().
+		Strict().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_2).
+		On(test_input_1).Go(test_state_1).
+		AddState(test_state_2).
+		Build()
+	switch e := err.(type) {
+	case DuplicateTransitionError:
+		if e.State != test_state_1 || e.Input != test_input_1 {
+			t.Errorf("Wrong error details: %+v", e)
+		}
+		t.Logf("Builder corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("Builder returned wrong error type: %T", err)
+	}
+}
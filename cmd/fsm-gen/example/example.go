@@ -0,0 +1,4 @@
+// Package example holds an FSM generated by fsm-gen from machine.yaml.
+package example
+
+//go:generate go run github.com/maxim0r/fsm/cmd/fsm-gen -in machine.yaml
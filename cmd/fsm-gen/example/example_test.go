@@ -0,0 +1,48 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/maxim0r/fsm"
+)
+
+func TestRoundTrip(t *testing.T) {
+	started := false
+	actions := map[string]fsm.Action{
+		"start": func(ctx context.Context) (context.Context, fsm.Input) { started = true; return ctx, fsm.NO_INPUT },
+	}
+
+	generated, err := NewFSM(actions)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	r, err := os.Open("machine.yaml")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer r.Close()
+	loaded, err := fsm.DefineFromYAML(r, actions)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if diff := generated.DefinitionDiff(loaded); len(diff) != 0 {
+		t.Errorf("Generated and loaded definitions differ: %v", diff)
+	}
+	gs, gi := generated.Names()
+	ls, li := loaded.Names()
+	if fmt.Sprint(gs, gi) != fmt.Sprint(ls, li) {
+		t.Errorf("Generated and loaded names differ. (Expected: %v %v, Got: %v %v)", ls, li, gs, gi)
+	}
+
+	if err := generated.Step(InputStart); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !started || generated.Current() != StateWaitingForInput {
+		t.Errorf("Wrong state. (Expected: %v, Got: %v)", StateWaitingForInput, generated.Current())
+	}
+}
@@ -0,0 +1,61 @@
+// Code generated by fsm-gen from machine.yaml. DO NOT EDIT.
+
+package example
+
+import "github.com/maxim0r/fsm"
+
+// States of the FSM defined in machine.yaml.
+const (
+	StateIdle            = 0
+	StateWaitingForInput = 1
+	StateDone            = 2
+)
+
+// Inputs of the FSM defined in machine.yaml.
+const (
+	InputStart  fsm.Input = 0
+	InputCancel fsm.Input = 1
+	Input2      fsm.Input = 2
+)
+
+var stateNames = map[int]string{
+	StateIdle:            "idle",
+	StateWaitingForInput: "waiting for input",
+	StateDone:            "done",
+}
+
+var inputNames = map[fsm.Input]string{
+	InputStart:  "start",
+	InputCancel: "cancel",
+}
+
+// NewFSM defines the FSM described in machine.yaml, named with its state and input names.
+// Outcomes naming an action run the one found under that name in actions,
+// or else the one registered with fsm.RegisterAction.
+func NewFSM(actions map[string]fsm.Action) (*fsm.FSM, error) {
+	f, err := fsm.Define(
+		fsm.State{
+			Index: StateIdle,
+			Outcomes: map[fsm.Input]fsm.Outcome{
+				InputStart: {State: StateWaitingForInput, ActionName: "start", Action: actions["start"]},
+				Input2:     {State: fsm.SAME_STATE, Action: fsm.NO_ACTION},
+			},
+		},
+		fsm.State{
+			Index: StateWaitingForInput,
+			Outcomes: map[fsm.Input]fsm.Outcome{
+				InputCancel:   {State: StateIdle, Action: fsm.NO_ACTION, Meta: map[string]string{"color": "green"}},
+				fsm.ANY_INPUT: {State: StateDone, Action: fsm.NO_ACTION},
+			},
+		},
+		fsm.State{
+			Index:    StateDone,
+			Outcomes: map[fsm.Input]fsm.Outcome{},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	f.SetLogger(nil, stateNames, inputNames)
+	return f, nil
+}
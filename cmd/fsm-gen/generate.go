@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/maxim0r/fsm"
+	"gopkg.in/yaml.v2"
+)
+
+// definition mirrors the document read by fsm.DefineFromJSON and fsm.DefineFromYAML.
+type definition struct {
+	States     []stateDefinition    `json:"states" yaml:"states"`
+	StateNames map[int]string       `json:"state_names" yaml:"state_names"`
+	InputNames map[fsm.Input]string `json:"input_names" yaml:"input_names"`
+}
+
+type stateDefinition struct {
+	Index    int                 `json:"index" yaml:"index"`
+	Outcomes []outcomeDefinition `json:"outcomes" yaml:"outcomes"`
+}
+
+type outcomeDefinition struct {
+	Input  fsm.Input         `json:"input" yaml:"input"`
+	State  int               `json:"state" yaml:"state"`
+	Action string            `json:"action" yaml:"action"`
+	Meta   map[string]string `json:"meta" yaml:"meta"`
+}
+
+// sentinels are the inputs and states the fsm package names itself.
+var (
+	sentinelInputs = map[fsm.Input]string{
+		fsm.ANY_INPUT:     "fsm.ANY_INPUT",
+		fsm.CHILD_DONE:    "fsm.CHILD_DONE",
+		fsm.ACTION_FAILED: "fsm.ACTION_FAILED",
+	}
+	sentinelStates = map[int]string{
+		fsm.SAME_STATE: "fsm.SAME_STATE",
+	}
+)
+
+// parse reads a JSON or YAML definition, rejecting unknown fields.
+// Documents starting with a brace are read as JSON, since YAML can't read the quoted keys of the name maps as numbers.
+func parse(data []byte) (definition, error) {
+	var d definition
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&d)
+		return d, err
+	}
+	err := yaml.UnmarshalStrict(data, &d)
+	return d, err
+}
+
+// generator holds the identifiers picked for the states and inputs of a definition.
+type generator struct {
+	d      definition
+	states map[int]string
+	inputs map[fsm.Input]string
+}
+
+// generate returns the gofmt-ed Go source for a definition read from the file source.
+func generate(d definition, source, pkg, fn string) ([]byte, error) {
+	if len(d.States) == 0 {
+		return nil, fsm.EmptyDefinitionError{}
+	}
+
+	g := generator{d: d, states: map[int]string{}, inputs: map[fsm.Input]string{}}
+	if err := g.name(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by fsm-gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import \"github.com/maxim0r/fsm\"\n\n")
+
+	fmt.Fprintf(&b, "// States of the FSM defined in %s.\nconst (\n", source)
+	for _, index := range sortedStates(g.states) {
+		fmt.Fprintf(&b, "\t%s = %d\n", g.states[index], index)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	if len(g.inputs) > 0 {
+		fmt.Fprintf(&b, "// Inputs of the FSM defined in %s.\nconst (\n", source)
+		for _, in := range sortedInputs(g.inputs) {
+			fmt.Fprintf(&b, "\t%s fsm.Input = %d\n", g.inputs[in], in)
+		}
+		fmt.Fprintf(&b, ")\n\n")
+	}
+
+	fmt.Fprintf(&b, "var stateNames = map[int]string{\n")
+	for _, index := range sortedStates(d.StateNames) {
+		fmt.Fprintf(&b, "\t%s: %q,\n", g.state(index), d.StateNames[index])
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "var inputNames = map[fsm.Input]string{\n")
+	for _, in := range sortedInputs(d.InputNames) {
+		fmt.Fprintf(&b, "\t%s: %q,\n", g.input(in), d.InputNames[in])
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// %s defines the FSM described in %s, named with its state and input names.\n", fn, source)
+	fmt.Fprintf(&b, "// Outcomes naming an action run the one found under that name in actions,\n")
+	fmt.Fprintf(&b, "// or else the one registered with fsm.RegisterAction.\n")
+	fmt.Fprintf(&b, "func %s(actions map[string]fsm.Action) (*fsm.FSM, error) {\n", fn)
+	fmt.Fprintf(&b, "\tf, err := fsm.Define(\n")
+	for _, s := range d.States {
+		fmt.Fprintf(&b, "\t\tfsm.State{\n\t\t\tIndex: %s,\n", g.state(s.Index))
+		fmt.Fprintf(&b, "\t\t\tOutcomes: map[fsm.Input]fsm.Outcome{\n")
+		for _, o := range s.Outcomes {
+			fmt.Fprintf(&b, "\t\t\t\t%s: {State: %s", g.input(o.Input), g.state(o.State))
+			if o.Action == "" {
+				fmt.Fprintf(&b, ", Action: fsm.NO_ACTION")
+			} else {
+				fmt.Fprintf(&b, ", ActionName: %q, Action: actions[%q]", o.Action, o.Action)
+			}
+			if len(o.Meta) > 0 {
+				fmt.Fprintf(&b, ", Meta: map[string]string{")
+				for _, k := range sortedKeys(o.Meta) {
+					fmt.Fprintf(&b, "%q: %q, ", k, o.Meta[k])
+				}
+				fmt.Fprintf(&b, "}")
+			}
+			fmt.Fprintf(&b, "},\n")
+		}
+		fmt.Fprintf(&b, "\t\t\t},\n\t\t},\n")
+	}
+	fmt.Fprintf(&b, "\t)\n")
+	fmt.Fprintf(&b, "\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(&b, "\tf.SetLogger(nil, stateNames, inputNames)\n")
+	fmt.Fprintf(&b, "\treturn f, nil\n}\n")
+
+	return format.Source(b.Bytes())
+}
+
+// name picks an identifier for every state of the definition, and every input it uses or names.
+// Will return an error if two of them end up with the same identifier.
+func (g generator) name() error {
+	used := map[string]bool{}
+	pick := func(prefix, name string, value int) (string, error) {
+		id := identifier(prefix, name, value)
+		if used[id] {
+			return "", fmt.Errorf("two constants named %s", id)
+		}
+		used[id] = true
+		return id, nil
+	}
+
+	var err error
+	for _, s := range g.d.States {
+		if _, ok := g.states[s.Index]; ok {
+			return fsm.ClashingStateError(s.Index)
+		}
+		if g.states[s.Index], err = pick("State", g.d.StateNames[s.Index], s.Index); err != nil {
+			return err
+		}
+	}
+
+	inputs := map[fsm.Input]string{}
+	for in, name := range g.d.InputNames {
+		inputs[in] = name
+	}
+	for _, s := range g.d.States {
+		for _, o := range s.Outcomes {
+			inputs[o.Input] = g.d.InputNames[o.Input]
+		}
+	}
+	for _, in := range sortedInputs(inputs) {
+		if _, ok := sentinelInputs[in]; ok {
+			continue
+		}
+		if g.inputs[in], err = pick("Input", inputs[in], int(in)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// state returns the Go expression for a state index.
+func (g generator) state(index int) string {
+	if id, ok := g.states[index]; ok {
+		return id
+	}
+	if id, ok := sentinelStates[index]; ok {
+		return id
+	}
+	return fmt.Sprint(index)
+}
+
+// input returns the Go expression for an input.
+func (g generator) input(in fsm.Input) string {
+	if id, ok := g.inputs[in]; ok {
+		return id
+	}
+	if id, ok := sentinelInputs[in]; ok {
+		return id
+	}
+	return fmt.Sprintf("fsm.Input(%d)", in)
+}
+
+// identifier turns a name into an exported CamelCase identifier with the given prefix,
+// falling back to the value when the name has no letters or digits: "waiting for input" becomes StateWaitingForInput.
+func identifier(prefix, name string, value int) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	if b.Len() == len(prefix) {
+		if value < 0 {
+			fmt.Fprintf(&b, "Minus%d", -value)
+		} else {
+			fmt.Fprintf(&b, "%d", value)
+		}
+	}
+	return b.String()
+}
+
+func sortedStates(m map[int]string) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func sortedInputs(m map[fsm.Input]string) []fsm.Input {
+	keys := make([]fsm.Input, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
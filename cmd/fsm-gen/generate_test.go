@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGenerateExample(t *testing.T) {
+	data, err := ioutil.ReadFile("example/machine.yaml")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	d, err := parse(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	code, err := generate(d, "machine.yaml", "example", "NewFSM")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	golden, err := ioutil.ReadFile("example/machine_fsm.go")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(code, golden) {
+		t.Errorf("Generated code differs from example/machine_fsm.go, run go generate ./...:\n%s", code)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, doc := range []string{
+		`states: []`,
+		`{"states": [{"index": 0}, {"index": 0}]}`,
+		// Both inputs would be InputGo.
+		`{"states": [{"index": 0}], "input_names": {"0": "go", "1": "Go"}}`,
+		`{"states": [{"index": 0, "unknown": 1}]}`,
+		"states: [{index: 0, unknown: 1}]",
+	} {
+		d, err := parse([]byte(doc))
+		if err == nil {
+			_, err = generate(d, "test.json", "test", "NewFSM")
+		}
+		if err == nil {
+			t.Errorf("No error for %s", doc)
+		}
+		t.Logf("fsm-gen corrently returned error: %v", err)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	d, err := parse([]byte(`{
+		"states": [{"index": 0, "outcomes": [{"input": 3, "state": 0, "action": "tick"}]}],
+		"state_names": {"0": "idle"},
+		"input_names": {"3": "tick"}
+	}`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if d.StateNames[0] != "idle" || d.InputNames[3] != "tick" || d.States[0].Outcomes[0].Action != "tick" {
+		t.Errorf("Wrong definition: %+v", d)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		value    int
+		expected string
+	}{
+		{"idle", 0, "StateIdle"},
+		{"waiting for input", 1, "StateWaitingForInput"},
+		{"HTTP-error 404", 2, "StateHTTPError404"},
+		{"", 3, "State3"},
+		{"--", -1, "StateMinus1"},
+	} {
+		if got := identifier("State", c.name, c.value); got != c.expected {
+			t.Errorf("Wrong identifier for %q. (Expected: %v, Got: %v)", c.name, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Command fsm-gen generates Go code from a JSON or YAML FSM definition, as read by fsm.DefineFromJSON
+// and fsm.DefineFromYAML: typed constants for the states and inputs, the name maps, and a constructor
+// calling fsm.Define. It is meant to be run by go generate:
+//
+//	//go:generate fsm-gen -in machine.yaml
+//
+// Constants are named after the state_names and input_names of the definition, in CamelCase,
+// with a State or Input prefix, and after their values when unnamed: StateIdle, Input3.
+//
+// Flags:
+//
+//	-in    JSON or YAML definition to read
+//	-out   file to write, by default the definition's name with an _fsm.go suffix
+//	-pkg   package of the generated file, by default $GOPACKAGE as set by go generate
+//	-func  name of the constructor, NewFSM by default
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "", "JSON or YAML definition to read")
+	out := flag.String("out", "", "Go file to write (default: <in>_fsm.go)")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated file")
+	fn := flag.String("func", "NewFSM", "name of the generated constructor")
+	flag.Parse()
+
+	if *in == "" || *pkg == "" {
+		fmt.Fprintln(os.Stderr, "fsm-gen: -in and -pkg are required outside of go generate")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(*in, filepath.Ext(*in)) + "_fsm.go"
+	}
+
+	if err := run(*in, *out, *pkg, *fn); err != nil {
+		fmt.Fprintln(os.Stderr, "fsm-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the code for the definition in the file in, and writes it to the file out.
+func run(in, out, pkg, fn string) error {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	d, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", in, err)
+	}
+	code, err := generate(d, filepath.Base(in), pkg, fn)
+	if err != nil {
+		return fmt.Errorf("%s: %v", in, err)
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
@@ -0,0 +1,100 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DefinitionEquals reports whether two FSMs have the same definition:
+// the same initial state, the same states, and the same transitions between them.
+// Weighted Outcomes must list the same targets with the same weights, in the same order.
+// Actions can't be compared, so transitions only differ on actions if one has an action and the other doesn't.
+// Current states, names and settings are ignored.
+// This method is thread-safe.
+func (f *FSM) DefinitionEquals(other *FSM) bool {
+	return len(f.DefinitionDiff(other)) == 0
+}
+
+// DefinitionDiff lists the differences between the definitions of two FSMs, as compared by DefinitionEquals.
+// Each difference is described by a human-readable line, sorted by state and then by input.
+// This method is thread-safe.
+func (f *FSM) DefinitionDiff(other *FSM) []string {
+	a, aInitial := f.definition()
+	b, bInitial := other.definition()
+
+	diff := []string{}
+	if aInitial != bInitial {
+		diff = append(diff, fmt.Sprintf("initial state: %d != %d", aInitial, bInitial))
+	}
+
+	for _, index := range unionStates(a, b) {
+		sa, inA := a[index]
+		sb, inB := b[index]
+		switch {
+		case !inB:
+			diff = append(diff, fmt.Sprintf("state %d: only in first", index))
+			continue
+		case !inA:
+			diff = append(diff, fmt.Sprintf("state %d: only in second", index))
+			continue
+		}
+
+		inputs := map[Input]Outcome{}
+		for in, do := range sa.Outcomes {
+			inputs[in] = do
+		}
+		for in, do := range sb.Outcomes {
+			inputs[in] = do
+		}
+		for _, in := range sortedInputs(inputs) {
+			da, inA := sa.Outcomes[in]
+			db, inB := sb.Outcomes[in]
+			switch {
+			case !inB:
+				diff = append(diff, fmt.Sprintf("state %d input %d: only in first", index, in))
+			case !inA:
+				diff = append(diff, fmt.Sprintf("state %d input %d: only in second", index, in))
+			case da.State != db.State:
+				diff = append(diff, fmt.Sprintf("state %d input %d: target %d != %d", index, in, da.State, db.State))
+			case !sameWeights(da.Weights, db.Weights):
+				diff = append(diff, fmt.Sprintf("state %d input %d: weights %v != %v", index, in, da.Weights, db.Weights))
+			case da.hasAction() != db.hasAction():
+				diff = append(diff, fmt.Sprintf("state %d input %d: action %v != %v", index, in, da.hasAction(), db.hasAction()))
+			}
+		}
+	}
+	return diff
+}
+
+// definition returns a copy of the state map and the initial state.
+func (f *FSM) definition() (map[int]State, int) {
+	f.RLock()
+	defer f.RUnlock()
+
+	states := make(map[int]State, len(f.states))
+	for index, s := range f.states {
+		states[index] = s
+	}
+	return states, f.initial
+}
+
+// hasAction reports whether the Outcome does anything besides changing state.
+// NO_ACTION doesn't count as an action.
+func (o Outcome) hasAction() bool {
+	if o.ActionE != nil {
+		return true
+	}
+	return o.Action != nil && reflect.ValueOf(o.Action).Pointer() != reflect.ValueOf(NO_ACTION).Pointer()
+}
+
+// unionStates returns the indices present in either state map, sorted ascending.
+func unionStates(a, b map[int]State) []int {
+	union := map[int]State{}
+	for index, s := range a {
+		union[index] = s
+	}
+	for index, s := range b {
+		union[index] = s
+	}
+	return sortedIndices(union)
+}
@@ -0,0 +1,104 @@
+package fsm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDefinitionDiff(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, NO_INPUT }},
+		},
+	}
+
+	a, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	// The same definition with different action functions is equal.
+	b, err := NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_2).Do(nil).
+		On(test_input_2).
+		AddState(test_state_2).
+		On(test_input_1).Go(test_state_1).Do(func(ctx context.Context) (context.Context, Input) { return ctx, test_input_1 }).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build FSM: ", err)
+	}
+	if !a.DefinitionEquals(b) {
+		t.Errorf("Equal definitions reported different: %v", a.DefinitionDiff(b))
+	}
+
+	c, err := NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_3).
+		On(test_input_3).
+		AddState(test_state_2).
+		On(test_input_1).Go(test_state_1).
+		AddState(test_state_3).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build FSM: ", err)
+	}
+
+	expected := []string{
+		"state 0 input 0: target 1 != 2",
+		"state 0 input 1: only in first",
+		"state 0 input 2: only in second",
+		"state 1 input 0: action true != false",
+		"state 2: only in second",
+	}
+	diff := a.DefinitionDiff(c)
+	if len(diff) != len(expected) {
+		t.Fatalf("Wrong diff. (Expected: %q, Got: %q)", expected, diff)
+	}
+	for i := range expected {
+		if diff[i] != expected[i] {
+			t.Fatalf("Wrong diff. (Expected: %q, Got: %q)", expected, diff)
+		}
+	}
+	if a.DefinitionEquals(c) {
+		t.Errorf("Different definitions reported equal.")
+	}
+}
+
+func TestDefinitionDiffWeights(t *testing.T) {
+	define := func(targets ...WeightedTarget) *FSM {
+		fsm, err := Define(
+			State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: WeightedOutcome(targets...)}},
+			State{Index: test_state_2},
+			State{Index: test_state_3},
+		)
+		if err != nil {
+			t.Fatal("Failed to define FSM: ", err)
+		}
+		return fsm
+	}
+
+	a := define(WeightedTarget{State: test_state_2, Weight: 1}, WeightedTarget{State: test_state_3, Weight: 1})
+	if !a.DefinitionEquals(define(WeightedTarget{State: test_state_2, Weight: 1}, WeightedTarget{State: test_state_3, Weight: 1})) {
+		t.Errorf("Equal weighted definitions reported different.")
+	}
+	for _, b := range []*FSM{
+		define(WeightedTarget{State: test_state_2, Weight: 1}, WeightedTarget{State: test_state_3, Weight: 3}),
+		define(WeightedTarget{State: test_state_2, Weight: 1}),
+	} {
+		diff := a.DefinitionDiff(b)
+		if len(diff) != 1 || !strings.HasPrefix(diff[0], "state 0 input 0: weights ") {
+			t.Errorf("Wrong diff. (Expected: weights, Got: %q)", diff)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package fsm
+
+import (
+	"context"
+)
+
+// payloadKey is the context key under which SpinWith stores the payload of an input.
+type payloadKey struct{}
+
+// valueKey wraps the keys used with WithValue, so they can't collide with context keys of other packages,
+// even when they share the same underlying value.
+type valueKey struct {
+	key interface{}
+}
+
+// WithValue returns a copy of ctx carrying val under key, for actions to pass data down an action chain.
+// Since Spin threads the context returned by each action into the next one, an action can store
+// a value with WithValue on the context it returns, and the following actions and hooks can read it with Value.
+// Keys only need to be comparable and unique among the users of the FSM, e.g. plain strings.
+func WithValue(ctx context.Context, key, val interface{}) context.Context {
+	return context.WithValue(ctx, valueKey{key}, val)
+}
+
+// Value returns the value stored under key with WithValue, or nil if there is none.
+func Value(ctx context.Context, key interface{}) interface{} {
+	return ctx.Value(valueKey{key})
+}
+
+// WithPayload returns a copy of ctx carrying a payload for the next input.
+// Actions can use it on the context they return to attach a payload to the input they chain.
+func WithPayload(ctx context.Context, payload interface{}) context.Context {
+	return context.WithValue(ctx, payloadKey{}, payload)
+}
+
+// Payload returns the payload attached to the input being processed, or nil if there is none.
+func Payload(ctx context.Context) interface{} {
+	return ctx.Value(payloadKey{})
+}
+
+// SpinWith spins the FSM like Spin, attaching a payload to the input.
+// Actions can retrieve it with Payload.
+// This method is thread-safe.
+func (f *FSM) SpinWith(ctx context.Context, in Input, payload interface{}) (context.Context, error) {
+	return f.Spin(WithPayload(ctx, payload), in)
+}
@@ -0,0 +1,87 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPayload(t *testing.T) {
+	ctx := context.Background()
+
+	var got []interface{}
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					got = append(got, Payload(ctx))
+					return WithPayload(ctx, "chained"), test_input_2
+				}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					got = append(got, Payload(ctx))
+					return ctx, NO_INPUT
+				}},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if _, err := fsm.SpinWith(ctx, test_input_1, 42); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(got) != 2 || got[0] != 42 || got[1] != "chained" {
+		t.Errorf("Wrong payloads. (Expected: %v, Got: %v)", []interface{}{42, "chained"}, got)
+	}
+}
+
+func TestValue(t *testing.T) {
+	ctx := context.Background()
+
+	var got interface{}
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					return WithValue(ctx, "user", 42), test_input_2
+				}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					got = Value(ctx, "user")
+					return ctx, NO_INPUT
+				}},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	ctx, err = fsm.Spin(ctx, test_input_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got != 42 || Value(ctx, "user") != 42 {
+		t.Errorf("Value not passed down the chain. (Expected: %v, Got: %v)", 42, got)
+	}
+
+	// Plain context keys don't collide with FSM values.
+	if context.WithValue(ctx, "user", 7).Value("user") != 7 || Value(context.WithValue(context.Background(), "user", 7), "user") != nil {
+		t.Errorf("FSM values collide with plain context keys.")
+	}
+}
@@ -0,0 +1,109 @@
+package fsm
+
+import (
+	"sort"
+	"sync"
+)
+
+// Coverage records which transitions of an FSM have fired, so tests can check they exercised all of them.
+type Coverage struct {
+	mu    sync.Mutex
+	all   [][2]int
+	fired map[[2]int]bool
+}
+
+// CoverageTracker starts recording which (state, input) transitions fire during Spin, and returns the record.
+// Transitions matched through ANY_INPUT are recorded under ANY_INPUT.
+// Tracking is off until this is called, and calling it again starts a fresh record.
+// This method is thread-safe.
+func (f *FSM) CoverageTracker() *Coverage {
+	f.Lock()
+	defer f.Unlock()
+
+	c := &Coverage{fired: map[[2]int]bool{}}
+	for _, index := range f.sortedStates() {
+		for _, in := range sortedInputs(f.states[index].Outcomes) {
+			c.all = append(c.all, [2]int{index, int(in)})
+		}
+	}
+	f.coverage = c
+	return c
+}
+
+// Uncovered returns the (state, input) pairs of the definition which haven't fired yet,
+// sorted by state and then by input.
+// This method is thread-safe.
+func (c *Coverage) Uncovered() [][2]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uncovered := [][2]int{}
+	for _, t := range c.all {
+		if !c.fired[t] {
+			uncovered = append(uncovered, t)
+		}
+	}
+	return uncovered
+}
+
+func (c *Coverage) record(state int, in Input) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fired[[2]int{state, int(in)}] = true
+}
+
+// OnFirstVisit sets a callback run the first time Spin moves the FSM into each state, replacing any set before.
+// The state the FSM is in when the callback is set counts as visited already, as do the states visited before.
+// States are remembered until ClearVisited, which Reset doesn't call, so a new session can choose
+// whether to start afresh. Moves made by SetState, Undo and Reset don't count as visits.
+// The callback is run while Spin holds the FSM lock, so it should be quick and must not use the FSM.
+// A nil callback stops the notifications, but visits are still remembered.
+// This method is thread-safe.
+func (f *FSM) OnFirstVisit(fn func(state int)) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.visited == nil {
+		f.visited = map[int]bool{f.current: true}
+	}
+	f.onFirstVisit = fn
+}
+
+// ClearVisited forgets the states visited so far, apart from the current one,
+// so OnFirstVisit reports each of them again.
+// This method is thread-safe.
+func (f *FSM) ClearVisited() {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.visited != nil {
+		f.visited = map[int]bool{f.current: true}
+	}
+}
+
+// Visited returns the states visited since OnFirstVisit was first called or ClearVisited last was, sorted ascending.
+// This method is thread-safe.
+func (f *FSM) Visited() []int {
+	f.RLock()
+	defer f.RUnlock()
+
+	visited := []int{}
+	for index := range f.visited {
+		visited = append(visited, index)
+	}
+	sort.Ints(visited)
+	return visited
+}
+
+// visit records that the FSM entered its current state, running the OnFirstVisit callback if it is new.
+// The caller must hold the lock.
+func (f *FSM) visit() {
+	if f.visited == nil || f.visited[f.current] {
+		return
+	}
+	f.visited[f.current] = true
+	if f.onFirstVisit != nil {
+		f.onFirstVisit(f.current)
+	}
+}
@@ -0,0 +1,117 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2},
+			test_input_2: Outcome{State: test_state_1},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	coverage := fsm.CoverageTracker()
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_3, test_state_1)
+
+	uncovered := coverage.Uncovered()
+	if len(uncovered) != 1 || uncovered[0] != [2]int{test_state_1, test_input_2} {
+		t.Errorf("Wrong uncovered transitions. (Expected: %v, Got: %v)", [][2]int{{test_state_1, test_input_2}}, uncovered)
+	}
+
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+	if uncovered := coverage.Uncovered(); len(uncovered) != 0 {
+		t.Errorf("Transitions left uncovered: %v", uncovered)
+	}
+}
+
+func TestOnFirstVisit(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var firsts []int
+	fsm.OnFirstVisit(func(state int) { firsts = append(firsts, state) })
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_2, test_state_3)
+	if fmt.Sprint(firsts) != fmt.Sprint([]int{test_state_2, test_state_3}) {
+		t.Errorf("Wrong first visits. (Expected: %v, Got: %v)", []int{test_state_2, test_state_3}, firsts)
+	}
+	if got := fsm.Visited(); len(got) != 3 {
+		t.Errorf("Wrong visited states. (Expected: all 3, Got: %v)", got)
+	}
+
+	// Reset keeps the visited states, ClearVisited forgets them.
+	fsm.Reset()
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if len(firsts) != 2 {
+		t.Errorf("State reported twice after Reset: %v", firsts)
+	}
+	fsm.ClearVisited()
+	if got := fsm.Visited(); len(got) != 1 || got[0] != test_state_2 {
+		t.Errorf("Wrong visited states after ClearVisited. (Expected: %v, Got: %v)", []int{test_state_2}, got)
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if len(firsts) != 3 || firsts[2] != test_state_1 {
+		t.Errorf("State not reported again after ClearVisited: %v", firsts)
+	}
+
+	// Clones and pooled FSMs report their own first visits.
+	firsts = nil
+	clone := fsm.Clone()
+	assertState(t, ctx, clone, test_input_1, test_state_2)
+	if fmt.Sprint(firsts) != fmt.Sprint([]int{test_state_2}) {
+		t.Errorf("Wrong first visits of clone. (Expected: %v, Got: %v)", []int{test_state_2}, firsts)
+	}
+	pool := NewPool(fsm, 1)
+	pool.Put(clone)
+	firsts = nil
+	assertState(t, ctx, pool.Get(), test_input_1, test_state_2)
+	if fmt.Sprint(firsts) != fmt.Sprint([]int{test_state_2}) {
+		t.Errorf("Wrong first visits of pooled FSM. (Expected: %v, Got: %v)", []int{test_state_2}, firsts)
+	}
+}
@@ -0,0 +1,60 @@
+package fsm
+
+import (
+	"fmt"
+)
+
+// UnknownActionError indicates that a definition refers to an action by a name which wasn't provided.
+type UnknownActionError string
+
+func (err UnknownActionError) Error() string {
+	return fmt.Sprintf("definition refers to unknown action: %q", string(err))
+}
+
+// definition is the serializable form of an FSM, shared by the loaders.
+type definition struct {
+	States     []stateDefinition `json:"states" yaml:"states"`
+	StateNames map[int]string    `json:"state_names,omitempty" yaml:"state_names,omitempty"`
+	InputNames map[Input]string  `json:"input_names,omitempty" yaml:"input_names,omitempty"`
+}
+
+type stateDefinition struct {
+	Index    int                 `json:"index" yaml:"index"`
+	Outcomes []outcomeDefinition `json:"outcomes" yaml:"outcomes"`
+}
+
+type outcomeDefinition struct {
+	Input  Input             `json:"input" yaml:"input"`
+	State  int               `json:"state" yaml:"state"`
+	Action string            `json:"action,omitempty" yaml:"action,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`
+}
+
+// define builds an FSM from the definition, resolving action names against actions,
+// and then against the actions registered with RegisterAction.
+// Outcomes without an action name run NO_ACTION.
+// The name maps, if any, are set as by SetLogger, so Names returns them.
+func (d definition) define(actions map[string]Action) (*FSM, error) {
+	states := make([]State, 0, len(d.States))
+	for _, sd := range d.States {
+		s := State{Index: sd.Index, Outcomes: map[Input]Outcome{}}
+		for _, od := range sd.Outcomes {
+			do := Outcome{State: od.State, ActionName: od.Action, Meta: od.Meta}
+			if od.Action == "" {
+				do.Action = NO_ACTION
+			} else if a, ok := actions[od.Action]; ok {
+				do.Action = a
+			}
+			s.Outcomes[od.Input] = do
+		}
+		states = append(states, s)
+	}
+
+	f, err := Define(states...)
+	if err != nil {
+		return nil, err
+	}
+	f.stateNames = d.StateNames
+	f.inputNames = d.InputNames
+	return f, nil
+}
@@ -0,0 +1,96 @@
+package fsm
+
+import (
+	"fmt"
+)
+
+// StateInUseError indicates an attempt to remove a state which the FSM still depends on.
+type StateInUseError struct {
+	StateIndex int
+	Reason     string
+}
+
+func (err StateInUseError) Error() string {
+	return fmt.Sprintf("state in use: %s.  (State: %v)", err.Reason, err.StateIndex)
+}
+
+// AddState adds a state to a defined FSM.
+// Will return a ClashingStateError if a state with the same index exists,
+// an UndefinedTargetError if one of its Outcomes points to a state which doesn't,
+// an InvalidWeightError if the Weights of one of its Outcomes can't be drawn from,
+// or an UnknownActionError if one of its Outcomes names an action which isn't registered.
+// This method is thread-safe.
+func (f *FSM) AddState(s State) error {
+	f.Lock()
+	defer f.Unlock()
+
+	s, err := resolveActions(s)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := f.states[s.Index]; ok {
+		return ClashingStateError(s.Index)
+	}
+	for _, in := range sortedInputs(s.Outcomes) {
+		exists := func(index int) bool { return hasState(f.states, index) || index == s.Index }
+		if err := checkTarget(s.Index, in, s.Outcomes[in], exists); err != nil {
+			return err
+		}
+	}
+
+	f.log.Tracef("FSM: add state [%d][%s]", s.Index, f.getStateName(s.Index))
+	f.states[s.Index] = s
+	return nil
+}
+
+// RemoveState removes a state from a defined FSM.
+// Will return an ImpossibleStateError if there is no such state, or a StateInUseError
+// if it is the current, initial or error state, or a target of another state's Outcome or the default outcome,
+// including one listed in its Weights.
+// This method is thread-safe.
+func (f *FSM) RemoveState(index int) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.states[index]; !ok {
+		return ImpossibleStateError(index)
+	}
+	if index == f.current {
+		return StateInUseError{index, "current state"}
+	}
+	if index == f.initial {
+		return StateInUseError{index, "initial state"}
+	}
+	if f.errorState != nil && *f.errorState == index {
+		return StateInUseError{index, "error state"}
+	}
+	if f.fallback != nil && targets(*f.fallback, index) {
+		return StateInUseError{index, "target of default outcome"}
+	}
+	for _, from := range f.sortedStates() {
+		if from == index {
+			continue
+		}
+		s := f.states[from]
+		for _, in := range sortedInputs(s.Outcomes) {
+			if targets(s.Outcomes[in], index) {
+				return StateInUseError{index, fmt.Sprintf("target of state %d on input %d", from, in)}
+			}
+		}
+	}
+
+	f.log.Tracef("FSM: remove state [%d][%s]", index, f.getStateName(index))
+	delete(f.states, index)
+	return nil
+}
+
+// targets reports whether the Outcome may move to the state index, including through its Weights.
+func targets(do Outcome, index int) bool {
+	for _, target := range do.targets() {
+		if target == index {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAddRemoveState(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if err := fsm.AddState(state2); err == nil {
+		t.Errorf("Didn't error adding clashing state.")
+	}
+	if err := fsm.AddState(State{Index: test_state_3, Outcomes: map[Input]Outcome{test_input_1: {State: 7}}}); err == nil {
+		t.Errorf("Didn't error adding state with undefined target.")
+	}
+
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1},
+			test_input_2: Outcome{State: test_state_3},
+		},
+	}
+	if err := fsm.AddState(state3); err != nil {
+		t.Fatal("Failed to add state: ", err)
+	}
+	if err := fsm.SetState(test_state_3); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	// Referential integrity: current, initial and targeted states can't be removed.
+	for _, index := range []int{test_state_1, test_state_2} {
+		err := fsm.RemoveState(index)
+		switch err.(type) {
+		case StateInUseError:
+			t.Logf("FSM corrently returned error: %v", err.Error())
+		default:
+			t.Fatalf("FSM returned wrong error type: %T", err)
+		}
+	}
+
+	if err := fsm.RemoveState(test_state_3); err != nil {
+		t.Fatal("Failed to remove state: ", err)
+	}
+	if fsm.StateCount() != 2 {
+		t.Errorf("Wrong state count after removal. (Expected: %v, Got: %v)", 2, fsm.StateCount())
+	}
+	if err := fsm.RemoveState(test_state_3); err == nil {
+		t.Errorf("Didn't error removing missing state.")
+	}
+}
+
+func TestRemoveWeightedTarget(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: WeightedOutcome(WeightedTarget{State: test_state_1, Weight: 1}, WeightedTarget{State: test_state_2, Weight: 1}),
+		},
+	}
+
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3},
+		},
+	}
+
+	fsm, err := Define(state1, state2, State{Index: test_state_3})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if err := fsm.SetDefaultOutcome(WeightedOutcome(WeightedTarget{State: test_state_3, Weight: 1})); err != nil {
+		t.Fatal("Failed to set default outcome: ", err)
+	}
+
+	// States only listed in Weights are still in use.
+	for _, index := range []int{test_state_2, test_state_3} {
+		err := fsm.RemoveState(index)
+		switch err.(type) {
+		case StateInUseError:
+			t.Logf("FSM corrently returned error: %v", err.Error())
+		default:
+			t.Fatalf("FSM returned wrong error type: %T", err)
+		}
+	}
+	if err := fsm.Validate(); err != nil {
+		t.Errorf("FSM invalid after refused removals: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package fsm
+
+import (
+	"context"
+)
+
+// An ErrorRouting tells Spin what to do after moving the FSM to the error state set by SetErrorState.
+type ErrorRouting int
+
+const (
+	// ErrorRoutingContinue makes Spin carry on from the error state and return no error for the failed action.
+	// This is the default.
+	ErrorRoutingContinue ErrorRouting = iota
+	// ErrorRoutingReturn makes Spin stop in the error state and return the error of the failed action.
+	ErrorRoutingReturn
+)
+
+// routedErrorKey is the context key under which Spin stores the error of an action routed to the error state.
+type routedErrorKey struct{}
+
+// SetErrorState makes Spin move the FSM to the given state whenever an action fails,
+// instead of leaving it in the state the action was run from.
+// The move is a regular transition from the state the action was run from:
+// the OnEnter hook of the error state is run and subscribers are notified.
+// The error Spin would have returned, an ActionError or an ActionTimeoutError, is stored in the context,
+// where the error state's hooks and actions can read it with RoutedError.
+// What happens next depends on the routing set by SetErrorRouting.
+// Under ErrorRoutingContinue, ACTION_FAILED is chained if the error state has an Outcome for it.
+// Under ErrorRoutingReturn, the chain stops and the error is returned.
+// Actions failing in the error state itself aren't routed, so their errors are always returned.
+// Will return an ImpossibleStateError, leaving the error state unchanged, if the state isn't part of the FSM definition.
+// The error state can't be removed with RemoveState.
+// This method is thread-safe.
+func (f *FSM) SetErrorState(index int) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.states[index]; !ok {
+		return ImpossibleStateError(index)
+	}
+	f.errorState = &index
+	return nil
+}
+
+// SetErrorRouting sets what Spin does after moving the FSM to the error state set by SetErrorState.
+// This method is thread-safe.
+func (f *FSM) SetErrorRouting(r ErrorRouting) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.errorRouting = r
+}
+
+// RoutedError returns the error of the action which made Spin move the FSM to its error state,
+// or nil if there is none.
+func RoutedError(ctx context.Context) error {
+	err, _ := ctx.Value(routedErrorKey{}).(error)
+	return err
+}
+
+// routeError returns the error state an action which failed in state from should be routed to,
+// and whether it should be routed at all.
+// The caller must hold the lock.
+func (f *FSM) routeError(from int) (State, bool) {
+	if f.errorState == nil || *f.errorState == from {
+		return State{}, false
+	}
+	s, ok := f.states[*f.errorState]
+	return s, ok
+}
@@ -0,0 +1,128 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSetErrorState(t *testing.T) {
+	ctx := context.Background()
+
+	failure := errors.New("boom")
+	var seen error
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, ActionE: func(ctx context.Context) (context.Context, Input, error) {
+				return ctx, NO_INPUT, failure
+			}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+	// The error state inspects the error, then recovers to state 1.
+	errorState := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			ACTION_FAILED: Outcome{State: test_state_1, Action: func(ctx context.Context) (context.Context, Input) {
+				seen = RoutedError(ctx)
+				return ctx, NO_INPUT
+			}},
+		},
+	}
+
+	fsm, err := Define(state1, state2, errorState)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if err := fsm.SetErrorState(test_state_3); err != nil {
+		t.Fatal("Failed to set error state: ", err)
+	}
+	var changes [][2]int
+	fsm.OnBeforeStateChange(func(from, to int) { changes = append(changes, [2]int{from, to}) })
+
+	_, hops, err := fsm.SpinVerbose(ctx, test_input_1)
+	if err != nil {
+		t.Fatalf("Routed error returned: %v", err)
+	}
+	// The routed hop goes to the error state.
+	if len(changes) != 2 || changes[0] != [2]int{test_state_1, test_state_3} || changes[1] != [2]int{test_state_3, test_state_1} {
+		t.Errorf("Wrong state changes: %v", changes)
+	}
+	fsm.OnBeforeStateChange(nil)
+	if len(hops) != 2 || hops[0].To != test_state_3 || hops[1].Input != ACTION_FAILED || fsm.Current() != test_state_1 {
+		t.Errorf("Wrong hops: %v", hops)
+	}
+	if e, ok := seen.(ActionError); !ok || e.Err != failure || e.StateIndex != test_state_1 {
+		t.Errorf("Wrong routed error: %v", seen)
+	}
+
+	// Route and return stops in the error state.
+	fsm.SetErrorRouting(ErrorRoutingReturn)
+	seen = nil
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch e := err.(type) {
+	case ActionError:
+		if e.Err != failure {
+			t.Errorf("Wrong action error. (Expected: %v, Got: %v)", failure, e.Err)
+		}
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Errorf("Wrong error type: %T (%v)", err, err)
+	}
+	if fsm.Current() != test_state_3 {
+		t.Errorf("Wrong state after routed error. (Expected: %v, Got: %v)", test_state_3, fsm.Current())
+	}
+	if seen != nil {
+		t.Error("Chain continued from the error state")
+	}
+
+	if unreachable := fsm.UnreachableStates(); len(unreachable) != 0 {
+		t.Errorf("Wrong unreachable states. (Expected: [], Got: %v)", unreachable)
+	}
+}
+
+func TestRemoveErrorState(t *testing.T) {
+	fsm, err := Define(State{Index: test_state_1}, State{Index: test_state_2})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if err := fsm.SetErrorState(test_state_2); err != nil {
+		t.Fatal("Failed to set error state: ", err)
+	}
+
+	err = fsm.RemoveState(test_state_2)
+	switch e := err.(type) {
+	case StateInUseError:
+		if e.Reason != "error state" {
+			t.Errorf("Wrong reason. (Expected: %q, Got: %q)", "error state", e.Reason)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestSetErrorStateUndefined(t *testing.T) {
+	fsm, err := Define(State{Index: test_state_1})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	err = fsm.SetErrorState(test_state_3)
+	switch e := err.(type) {
+	case ImpossibleStateError:
+		if int(e) != test_state_3 {
+			t.Errorf("Wrong state. (Expected: %v, Got: %v)", test_state_3, int(e))
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.errorState != nil {
+		t.Errorf("Undefined error state was set: %v", *fsm.errorState)
+	}
+}
@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"time"
+)
+
+// SUBSCRIBER_BUFFER is the number of transitions buffered for each subscriber.
+// Transitions sent to a subscriber whose buffer is full are dropped.
+const SUBSCRIBER_BUFFER = 64
+
+// A Transition describes a single hop an FSM made from one state to another.
+// Halted is set when the action of the hop returned HALT to end the chain on purpose.
+type Transition struct {
+	From   int
+	To     int
+	Input  Input
+	Time   time.Time
+	Halted bool
+}
+
+// Subscribe returns a channel which receives a Transition for every hop the FSM makes.
+// Spin never blocks on subscribers: if the channel buffer is full the transition is dropped.
+// This method is thread-safe.
+func (f *FSM) Subscribe() <-chan Transition {
+	f.Lock()
+	defer f.Unlock()
+
+	ch := make(chan Transition, SUBSCRIBER_BUFFER)
+	f.subs = append(f.subs, ch)
+	return ch
+}
+
+// SubscribeWithReplay subscribes like Subscribe, but the channel first receives up to the last n transitions
+// recorded in the history, oldest first, followed by the live ones, without gaps or duplicates in between.
+// Only transitions kept by EnableHistory can be replayed, so nothing is replayed while the history is disabled.
+// The channel buffer is enlarged to hold the replayed transitions on top of SUBSCRIBER_BUFFER.
+// This method is thread-safe.
+func (f *FSM) SubscribeWithReplay(n int) <-chan Transition {
+	f.Lock()
+	defer f.Unlock()
+
+	replay := f.history
+	if n < len(replay) {
+		if n < 0 {
+			n = 0
+		}
+		replay = replay[len(replay)-n:]
+	}
+
+	ch := make(chan Transition, SUBSCRIBER_BUFFER+len(replay))
+	for _, t := range replay {
+		ch <- t
+	}
+	f.subs = append(f.subs, ch)
+	return ch
+}
+
+// Unsubscribe stops sending transitions to a channel returned by Subscribe, and closes it.
+// Unknown channels are ignored.
+// This method is thread-safe.
+func (f *FSM) Unsubscribe(ch <-chan Transition) {
+	f.Lock()
+	defer f.Unlock()
+
+	for i, sub := range f.subs {
+		if sub == ch {
+			close(sub)
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// EnableHistory makes the FSM remember its last size transitions, discarding the oldest ones first.
+// A size of zero or less disables the history and forgets any recorded transitions.
+// This method is thread-safe.
+func (f *FSM) EnableHistory(size int) {
+	f.Lock()
+	defer f.Unlock()
+
+	if size <= 0 {
+		size = 0
+	}
+	f.historyLen = size
+	if len(f.history) > size {
+		f.history = append([]Transition(nil), f.history[len(f.history)-size:]...)
+	}
+}
+
+// History returns the recorded transitions, oldest first.
+// This method is thread-safe.
+func (f *FSM) History() []Transition {
+	f.RLock()
+	defer f.RUnlock()
+
+	return append([]Transition(nil), f.history...)
+}
+
+// notify records a transition in the history and metrics, and sends it to every subscriber without blocking.
+// During an atomic chain, the transition is held back until the chain commits.
+func (f *FSM) notify(t Transition) {
+	if f.pending != nil {
+		f.pending = append(f.pending, t)
+		return
+	}
+
+	if f.metrics != nil {
+		f.metrics.TransitionObserved(t.From, t.To, t.Input)
+	}
+
+	if f.historyLen > 0 {
+		if len(f.history) == f.historyLen {
+			copy(f.history, f.history[1:])
+			f.history = f.history[:len(f.history)-1]
+		}
+		f.history = append(f.history, t)
+	}
+
+	for _, sub := range f.subs {
+		select {
+		case sub <- t:
+		default:
+			f.log.Tracef("FSM: subscriber buffer full, dropped transition [%d] -> [%d]", t.From, t.To)
+		}
+	}
+}
+
+// OnBeforeStateChange sets a callback run on every hop once the action has completed, right before the FSM
+// moves from one state to the other, replacing any set before.
+// When the action fails, it is only run if the failure is routed to the error state set by SetErrorState,
+// with to being the error state.
+// Internal transitions and self-transitions call it too, with from equal to to.
+// The callback is run while Spin holds the FSM lock, so it should be quick and must not use the FSM.
+// A nil callback removes it.
+// This method is thread-safe.
+func (f *FSM) OnBeforeStateChange(fn func(from, to int)) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.beforeChange = fn
+}
@@ -0,0 +1,327 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	ch := fsm.Subscribe()
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+
+	expected := []Transition{
+		{From: test_state_1, To: test_state_2, Input: test_input_1},
+		{From: test_state_2, To: test_state_3, Input: test_input_2},
+	}
+	for _, e := range expected {
+		got := <-ch
+		if got.From != e.From || got.To != e.To || got.Input != e.Input {
+			t.Errorf("Wrong transition. (Expected: %v -> %v on %v, Got: %v -> %v on %v)", e.From, e.To, e.Input, got.From, got.To, got.Input)
+		}
+	}
+
+	fsm.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Errorf("Channel not closed after Unsubscribe.")
+	}
+}
+
+// Test that an abandoned subscriber can't block Spin.
+func TestSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	fsm.Subscribe()
+	for i := 0; i < SUBSCRIBER_BUFFER*2; i++ {
+		assertState(t, ctx, fsm, test_input_1, test_state_1)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_3 }},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.EnableHistory(3)
+
+	// A three hop chain: 1 -> 2 -> 3 -> 3.
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+
+	expected := []Transition{
+		{From: test_state_1, To: test_state_2, Input: test_input_1},
+		{From: test_state_2, To: test_state_3, Input: test_input_2},
+		{From: test_state_3, To: test_state_3, Input: test_input_3},
+	}
+	assertHistory(t, fsm.History(), expected)
+
+	// The oldest entry is dropped once the history is full.
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	expected = append(expected[1:], Transition{From: test_state_3, To: test_state_1, Input: test_input_1})
+	assertHistory(t, fsm.History(), expected)
+}
+
+func TestSubscribeWithReplay(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.EnableHistory(5)
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	// Only the last two are replayed, followed by the live transition.
+	ch := fsm.SubscribeWithReplay(2)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	var got []Transition
+	for i := 0; i < 3; i++ {
+		got = append(got, <-ch)
+	}
+	assertHistory(t, got, []Transition{
+		{From: test_state_2, To: test_state_1, Input: test_input_1},
+		{From: test_state_1, To: test_state_2, Input: test_input_1},
+		{From: test_state_2, To: test_state_1, Input: test_input_1},
+	})
+
+	// Asking for more than the history holds replays all of it.
+	all := fsm.SubscribeWithReplay(100)
+	if len(all) != 4 {
+		t.Errorf("Wrong number of replayed transitions. (Expected: 4, Got: %v)", len(all))
+	}
+}
+
+func assertHistory(t *testing.T, got []Transition, expected []Transition) {
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong history length. (Expected: %v, Got: %v)", len(expected), len(got))
+	}
+	for i, e := range expected {
+		if got[i].From != e.From || got[i].To != e.To || got[i].Input != e.Input {
+			t.Errorf("Wrong history entry %d. (Expected: %v -> %v on %v, Got: %v -> %v on %v)", i, e.From, e.To, e.Input, got[i].From, got[i].To, got[i].Input)
+		}
+	}
+}
+
+func TestHalt(t *testing.T) {
+	ctx := context.Background()
+
+	halt := true
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: func(ctx context.Context) (context.Context, Input) {
+				if halt {
+					return ctx, HALT
+				}
+				return ctx, test_input_2
+			}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.EnableHistory(5)
+
+	_, hops, err := fsm.SpinVerbose(ctx, test_input_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(hops) != 1 || !hops[0].Halted || hops[0].To != test_state_2 {
+		t.Errorf("Wrong hops for a halted chain: %+v", hops)
+	}
+	if h := fsm.History(); len(h) != 1 || !h[0].Halted {
+		t.Errorf("Halt not recorded in the history: %+v", h)
+	}
+
+	// Without HALT the chain carries on, and nothing is marked.
+	halt = false
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	_, hops, err = fsm.SpinVerbose(ctx, test_input_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(hops) != 2 || hops[0].Halted || hops[1].Halted || fsm.Current() != test_state_3 {
+		t.Errorf("Wrong hops for a complete chain: %+v", hops)
+	}
+
+	_, err = fsm.Spin(ctx, HALT)
+	switch err.(type) {
+	case NoInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestSpinVerbose(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	_, hops, err := fsm.SpinVerbose(ctx, test_input_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertHistory(t, hops, []Transition{
+		{From: test_state_1, To: test_state_2, Input: test_input_1},
+		{From: test_state_2, To: test_state_3, Input: test_input_2},
+	})
+}
+
+func TestOnBeforeStateChange(t *testing.T) {
+	var calls []string
+	record := func(s string) { calls = append(calls, s) }
+
+	state1 := State{
+		Index:  test_state_1,
+		OnExit: func(ctx context.Context) context.Context { record("exit 0"); return ctx },
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: func(ctx context.Context) (context.Context, Input) {
+				record("action 0")
+				return ctx, test_input_2
+			}},
+		},
+	}
+	state2 := State{
+		Index:   test_state_2,
+		OnEnter: func(ctx context.Context) context.Context { record("enter 1"); return ctx },
+		OnExit:  func(ctx context.Context) context.Context { record("exit 1"); return ctx },
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, Action: func(ctx context.Context) (context.Context, Input) {
+				record("action 1")
+				return ctx, NO_INPUT
+			}},
+		},
+	}
+	state3 := State{
+		Index:   test_state_3,
+		OnEnter: func(ctx context.Context) context.Context { record("enter 2"); return ctx },
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.OnBeforeStateChange(func(from, to int) {
+		record(fmt.Sprintf("change %d->%d", from, to))
+	})
+
+	if _, err := fsm.Spin(context.Background(), test_input_1); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []string{"exit 0", "action 0", "change 0->1", "enter 1", "exit 1", "action 1", "change 1->2", "enter 2"}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Errorf("Wrong order. (Expected: %v, Got: %v)", expected, calls)
+	}
+}
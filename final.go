@@ -0,0 +1,74 @@
+package fsm
+
+// SetFinalStates declares the states in which the FSM is considered done, replacing any set before.
+// Once Spin moves the FSM into one of them, the channel returned by Done is closed.
+// If the FSM is already in one of them, it is done right away.
+// This method is thread-safe.
+func (f *FSM) SetFinalStates(indices ...int) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.final = make(map[int]bool, len(indices))
+	for _, index := range indices {
+		f.final[index] = true
+	}
+	f.checkDone()
+}
+
+// Done returns a channel which is closed once Spin moves the FSM into one of the states set by SetFinalStates.
+// The channel stays closed until Reset, after which Done returns a new one.
+// This method is thread-safe.
+func (f *FSM) Done() <-chan struct{} {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.done == nil {
+		f.done = make(chan struct{})
+		f.checkDone()
+	}
+	return f.done
+}
+
+// IsDone reports whether the FSM is in one of the states set by SetFinalStates.
+// This method is thread-safe.
+func (f *FSM) IsDone() bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.final[f.current]
+}
+
+// checkDone closes the Done channel if the FSM is in a final state.
+// The caller must hold the lock.
+func (f *FSM) checkDone() {
+	if f.final[f.current] {
+		f.closeDone()
+	}
+}
+
+// closeDone closes the Done channel, unless it is already closed or was never handed out.
+// The caller must hold the lock.
+func (f *FSM) closeDone() {
+	if f.done == nil {
+		return
+	}
+	select {
+	case <-f.done:
+	default:
+		f.log.Tracef("FSM: done in final state [%d][%s]", f.current, f.getStateName(f.current))
+		close(f.done)
+	}
+}
+
+// reopenDone replaces a closed Done channel, so the FSM can be awaited again.
+// The caller must hold the lock.
+func (f *FSM) reopenDone() {
+	if f.done == nil {
+		return
+	}
+	select {
+	case <-f.done:
+		f.done = make(chan struct{})
+	default:
+	}
+}
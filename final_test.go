@@ -0,0 +1,67 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFinalStates(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetFinalStates(test_state_3)
+
+	done := fsm.Done()
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	select {
+	case <-done:
+		t.Fatalf("Done closed before reaching a final state.")
+	default:
+	}
+	if fsm.IsDone() {
+		t.Errorf("IsDone before reaching a final state.")
+	}
+
+	go func() {
+		if _, err := fsm.Spin(ctx, test_input_1); err != nil {
+			t.Error(err.Error())
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Done not closed after reaching a final state.")
+	}
+	if !fsm.IsDone() {
+		t.Errorf("Not IsDone in a final state.")
+	}
+
+	// Reset hands out a fresh channel.
+	fsm.Reset()
+	select {
+	case <-fsm.Done():
+		t.Errorf("Done closed after reset.")
+	default:
+	}
+}
@@ -6,13 +6,52 @@ package fsm
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	NO_INPUT Input = -1
+	// ANY_INPUT can be used as a key in State.Outcomes to catch every input the state doesn't list explicitly.
+	// An exact match always takes precedence over ANY_INPUT.
+	ANY_INPUT Input = -2
+	// CHILD_DONE is chained to a state whose Child machine reaches a terminal state,
+	// if the state has an Outcome for it. ANY_INPUT doesn't match it.
+	CHILD_DONE Input = -3
+	// ACTION_FAILED is chained to the error state set by SetErrorState when an action fails,
+	// if the error state has an Outcome for it. ANY_INPUT doesn't match it.
+	ACTION_FAILED Input = -4
+	// HALT can be returned by an action to end the chain on purpose, although it could go on.
+	// The chain stops like with NO_INPUT, but the Transition of the hop is marked as Halted.
+	HALT Input = -5
+)
+
+// CHOICE can be used as the State of an Outcome whose target is picked at spin time by its Chooser,
+// or drawn from its Weights: see WeightedOutcome.
+const CHOICE = math.MinInt32
+
+// SAME_STATE can be used as the State of an Outcome which runs its action but leaves the FSM where it is,
+// without repeating the index of the state: see Stay.
+// Like an explicit self-transition, it runs the state's OnExit and OnEnter hooks unless the Outcome is Internal.
+const SAME_STATE = math.MinInt32 + 1
+
+// DEFAULT_MAX_CHAIN_DEPTH is the number of hops a single Spin may take before it gives up on the chain.
+const DEFAULT_MAX_CHAIN_DEPTH = 1000
+
+// A Policy tells Spin what to do when the FSM is found in a state which isn't part of its definition.
+type Policy int
+
+const (
+	// PolicyError makes Spin return an ImpossibleStateError. This is the default.
+	PolicyError Policy = iota
+	// PolicyResetToInitial makes Spin move the FSM to its initial state and retry the input there.
+	PolicyResetToInitial
 )
 
 // An Input to give to an FSM.
@@ -25,40 +64,192 @@ type Action func(context.Context) (context.Context, Input)
 // NO_ACTION is useful for when you need a certain input to just change the state of the FSM without doing anytyhing else.
 func NO_ACTION(ctx context.Context) (context.Context, Input) { return ctx, NO_INPUT }
 
+// An ActionE is an Action which can fail.
+// When it returns an error, Spin stops the chain and leaves the FSM in the state it was in before the action.
+type ActionE func(context.Context) (context.Context, Input, error)
+
+// WrapAction adapts an Action which can't fail into an ActionE.
+func WrapAction(a Action) ActionE {
+	return func(ctx context.Context) (context.Context, Input, error) {
+		ctx, in := a(ctx)
+		return ctx, in, nil
+	}
+}
+
+// A Hook is a callback run when an FSM enters or leaves a state.
+type Hook func(context.Context) context.Context
+
+// A Guard decides at spin time whether an Outcome is allowed to fire.
+type Guard func(context.Context) bool
+
+// A Chooser picks the target state of a CHOICE Outcome at spin time.
+type Chooser func(context.Context) int
+
 // An Outcome describes the result of running an FSM.
 // It describes which state to move to next, and an Action to perform.
+// If ActionE is set it is run instead of Action, and if neither is set the Outcome behaves like NO_ACTION.
+// If Guard is set and returns false, the Outcome is skipped in favour of the next one matching the input: see Spin.
+// A nil Guard always allows the Outcome.
+// An Internal Outcome which stays in the same state only runs its action:
+// the state's OnExit and OnEnter hooks are skipped, and no Transition is published to subscribers or the history.
+// If State is CHOICE, Chooser is called before the action to pick the target state,
+// or if there is no Chooser, the target is drawn from Weights.
+// If ActionName is set while neither Action nor ActionE is, Define looks the action up among those registered
+// with RegisterAction. The name is kept either way, so the Outcome can be serialized back.
+// Meta carries arbitrary labels for tools reading the definition, e.g. through WalkTransitions;
+// the FSM itself doesn't interpret it.
 type Outcome struct {
-	State  int
-	Action Action
+	State      int
+	Action     Action
+	ActionE    ActionE
+	ActionName string
+	Guard      Guard
+	Internal   bool
+	Chooser    Chooser
+	Weights    []WeightedTarget
+	Meta       map[string]string
 }
 
 // A State describes one possible state of an FSM.
 // It maps Inputs to Outcomes.
+// OnExit is run before the Action of any Outcome leaving the state,
+// and OnEnter after the FSM has moved into the state, regardless of which input triggered the transition.
+// If Child is set, the state is a composite state: see Spin.
+// If Timeout is set, its Input is spun automatically when the FSM stays in the state for too long: see StateTimeout.
 type State struct {
 	Index    int
 	Outcomes map[Input]Outcome
+	OnEnter  Hook
+	OnExit   Hook
+	Child    *FSM
+	Timeout  *StateTimeout
+}
+
+// Stay returns an Outcome which runs action without changing state, using SAME_STATE.
+func Stay(action Action) Outcome {
+	return Outcome{State: SAME_STATE, Action: action}
+}
+
+// OutcomesFor returns an Outcomes map routing every one of the inputs to the same Outcome.
+// The map is new, so more Outcomes can be added to it, and it can be copied entry by entry into another one:
+//
+//	outcomes := OutcomesFor([]Input{in1, in2}, Outcome{State: s2})
+//	outcomes[in3] = Outcome{State: s3}
+func OutcomesFor(inputs []Input, o Outcome) map[Input]Outcome {
+	outcomes := make(map[Input]Outcome, len(inputs))
+	for _, in := range inputs {
+		outcomes[in] = o
+	}
+	return outcomes
+}
+
+// quietLogger is the default logger of every FSM, until SetLogger is called.
+// It discards everything, so a single one is shared instead of allocating one per FSM.
+var quietLogger = &logrus.Logger{
+	Out:       ioutil.Discard,
+	Formatter: new(logrus.TextFormatter),
+	Hooks:     make(logrus.LevelHooks),
+	Level:     logrus.FatalLevel,
 }
 
 // FSM is the main structure defining a Finite State Machine.
 type FSM struct {
-	sync.Mutex
-	states     map[int]State
-	current    int
-	log        *logrus.Logger
-	stateNames map[int]string
-	inputNames map[Input]string
+	sync.RWMutex
+	states        map[int]State
+	initial       int
+	current       int
+	maxDepth      int
+	subs          []chan Transition
+	history       []Transition
+	historyLen    int
+	undo          []int
+	undoLen       int
+	final         map[int]bool
+	tags          map[int]map[string]bool
+	done          chan struct{}
+	fallback      *Outcome
+	errorState    *int
+	errorRouting  ErrorRouting
+	actionTimeout time.Duration
+	metrics       Metrics
+	spinObserver  func(start int, in Input, hops int, d time.Duration)
+	onReject      func(state int, in Input)
+	beforeChange  func(from, to int)
+	policy        Policy
+	warnChecks    Check
+	paused        bool
+	atomic        bool
+	pending       []Transition
+	lastChanged   bool
+	override      *Outcome
+	timer         *time.Timer
+	timerGen      uint64
+	timersStopped bool
+	recoverFn     func(r interface{}) error
+	middleware    []Middleware
+	rand          *rand.Rand
+	started       bool
+	coverage      *Coverage
+	visited       map[int]bool
+	onFirstVisit  func(state int)
+	log           logrus.Ext1FieldLogger
+	errorLevel    *logrus.Level
+	stateNames    map[int]string
+	inputNames    map[Input]string
+	stateNamer    func(int) string
+	inputNamer    func(Input) string
+}
+
+// InputSource tells where an input processed by Spin came from.
+type InputSource int
+
+const (
+	// EXTERNAL_INPUT is an input passed to Spin by the caller.
+	EXTERNAL_INPUT InputSource = iota
+	// ACTION_INPUT is an input returned by an action, chained within a Spin.
+	ACTION_INPUT
+)
+
+func (src InputSource) String() string {
+	switch src {
+	case EXTERNAL_INPUT:
+		return "external"
+	case ACTION_INPUT:
+		return "action"
+	}
+	return fmt.Sprintf("InputSource(%d)", int(src))
 }
 
 // InvalidInputError indicates that an input was passed to an FSM which is not valid for its current state.
+// Source tells whether the input was passed to Spin, pointing at the caller,
+// or returned by an action partway through a chain, pointing at the action.
+// In the latter case Depth is the hop at which the chain stalled and Origin is the input passed to Spin.
+// Depth is 1 when the input passed to Spin was itself invalid.
 type InvalidInputError struct {
 	StateIndex int
 	Input      Input
+	Depth      int
+	Origin     Input
+	Source     InputSource
 }
 
 func (err InvalidInputError) Error() string {
+	if err.Source == ACTION_INPUT {
+		return fmt.Sprintf("input returned by action invalid in current state.  (State: %v, Input: %v, Depth: %v, Origin: %v)", err.StateIndex, err.Input, err.Depth, err.Origin)
+	}
 	return fmt.Sprintf("input invalid in current state.  (State: %v, Input: %v)", err.StateIndex, err.Input)
 }
 
+// GuardRejectedError indicates that an input was valid for the current state, but the Guard of its Outcome refused it.
+type GuardRejectedError struct {
+	StateIndex int
+	Input      Input
+}
+
+func (err GuardRejectedError) Error() string {
+	return fmt.Sprintf("input rejected by guard in current state.  (State: %v, Input: %v)", err.StateIndex, err.Input)
+}
+
 // ImpossibleStateError indicates that an FSM is in a state which wasn't part of its definition.
 // This indicates that either the definition is wrong, or someone is monkeying around with the FSM state manually.
 type ImpossibleStateError int
@@ -74,9 +265,116 @@ func (err ClashingStateError) Error() string {
 	return fmt.Sprintf("attempt to define FSM with clashing states. Index: %d", err)
 }
 
+// NoInputError indicates that NO_INPUT or HALT was passed to Spin, which would otherwise do nothing.
+type NoInputError struct {
+	StateIndex int
+}
+
+func (err NoInputError) Error() string {
+	return fmt.Sprintf("NO_INPUT passed to FSM.  (State: %v)", err.StateIndex)
+}
+
+// PausedError indicates that an input was passed to an FSM which is paused.
+type PausedError struct {
+	StateIndex int
+	Input      Input
+}
+
+func (err PausedError) Error() string {
+	return fmt.Sprintf("FSM is paused.  (State: %v, Input: %v)", err.StateIndex, err.Input)
+}
+
+// EmptyDefinitionError indicates that an attempt to define an FSM without any states was made.
+type EmptyDefinitionError struct{}
+
+func (err EmptyDefinitionError) Error() string {
+	return "attempt to define FSM with no states"
+}
+
+// UndefinedTargetError indicates that an Outcome points to a state which isn't part of the FSM definition.
+type UndefinedTargetError struct {
+	FromState int
+	Input     Input
+	Target    int
+}
+
+func (err UndefinedTargetError) Error() string {
+	return fmt.Sprintf("outcome targets undefined state.  (State: %v, Input: %v, Target: %v)", err.FromState, err.Input, err.Target)
+}
+
+// ActionError indicates that the action of an Outcome failed.
+// The FSM is left in the state the action was run from.
+type ActionError struct {
+	StateIndex int
+	Input      Input
+	Err        error
+}
+
+func (err ActionError) Error() string {
+	return fmt.Sprintf("action failed: %v.  (State: %v, Input: %v)", err.Err, err.StateIndex, err.Input)
+}
+
+// Unwrap returns the error returned by the action.
+func (err ActionError) Unwrap() error {
+	return err.Err
+}
+
+// ChainLimitExceededError indicates that actions kept chaining inputs for longer than the FSM allows.
+// This usually means that two or more actions feed each other in an endless loop.
+type ChainLimitExceededError struct {
+	Depth int
+	Input Input
+}
+
+func (err ChainLimitExceededError) Error() string {
+	return fmt.Sprintf("action chain exceeded maximum depth.  (Depth: %v, Input: %v)", err.Depth, err.Input)
+}
+
+// InterruptedError indicates that the context passed to Spin was done before the action chain completed.
+// The FSM is left in the state reached by the last completed transition.
+type InterruptedError struct {
+	StateIndex int
+	Input      Input
+	Err        error
+}
+
+func (err InterruptedError) Error() string {
+	return fmt.Sprintf("spin interrupted: %v.  (State: %v, Input: %v)", err.Err, err.StateIndex, err.Input)
+}
+
+// Unwrap returns the context error which interrupted the spin.
+func (err InterruptedError) Unwrap() error {
+	return err.Err
+}
+
 // Define an FSM from a list of States.
-// Will return an  error if you try to use two states with the same index.
+// The FSM starts in the first state of the list.
+// Will return an  error if you try to use two states with the same index,
+// an UndefinedTargetError if an Outcome points to a state which isn't in the list,
+// an InvalidWeightError if the Weights of an Outcome can't be drawn from,
+// or an UnknownActionError if an Outcome names an action which isn't registered.
+// Will return an EmptyDefinitionError if the list is empty.
 func Define(states ...State) (*FSM, error) {
+	if len(states) == 0 {
+		return nil, EmptyDefinitionError{}
+	}
+	return DefineWithStart(states[0].Index, states...)
+}
+
+// DefineSlice defines an FSM from a slice of States, exactly like Define(states...).
+// It is meant for code which builds the list of states programmatically.
+func DefineSlice(states []State) (*FSM, error) {
+	return Define(states...)
+}
+
+// DefineWithStart defines an FSM from a list of States like Define, but starts in the given state
+// instead of the first one in the list.
+// Will return an ImpossibleStateError if start isn't in the list, as well as any error Define would return.
+func DefineWithStart(start int, states ...State) (*FSM, error) {
+	if len(states) == 0 {
+		return nil, EmptyDefinitionError{}
+	}
+
 	stateMap := map[int]State{}
 	for _, s := range states {
 		if _, ok := stateMap[s.Index]; ok {
@@ -85,59 +383,1029 @@ func Define(states ...State) (*FSM, error) {
 		stateMap[s.Index] = s
 	}
 
-	// Set default logger
-	log := logrus.New()
-	log.Level = logrus.FatalLevel
+	for _, s := range states {
+		s, err := resolveActions(s)
+		if err != nil {
+			return nil, err
+		}
+		stateMap[s.Index] = s
+	}
+
+	for _, s := range states {
+		for _, in := range sortedInputs(s.Outcomes) {
+			exists := func(index int) bool { return hasState(stateMap, index) }
+			if err := checkTarget(s.Index, in, s.Outcomes[in], exists); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !hasState(stateMap, start) {
+		return nil, ImpossibleStateError(start)
+	}
 
 	return &FSM{
-		states:  stateMap,
-		current: states[0].Index,
-		log:     log,
+		states:   stateMap,
+		initial:  start,
+		current:  start,
+		maxDepth: DEFAULT_MAX_CHAIN_DEPTH,
+		log:      quietLogger,
 	}, nil
 }
 
+// MustDefine is like Define, but also runs Validate on the result, and panics if either returns an error.
+// It simplifies the safe initialization of package-level FSMs, in the spirit of regexp.MustCompile.
+// The panic message carries the error.
+// Targets picked at spin time by a Chooser aren't known, so a state only reached through a Chooser
+// fails CheckUnreachableStates: use MustDefineWithWarnings to downgrade that check.
+func MustDefine(states ...State) *FSM {
+	return MustDefineWithWarnings(0, states...)
+}
+
+// MustDefineWithWarnings is like MustDefine, but the given checks are only warnings,
+// as set by SetValidationWarnings before Validate runs, so they don't cause a panic.
+// The setting stays on the returned FSM.
+func MustDefineWithWarnings(warnings Check, states ...State) *FSM {
+	f, err := Define(states...)
+	if err == nil {
+		f.SetValidationWarnings(warnings)
+		err = f.Validate()
+	}
+	if err != nil {
+		panic("fsm: MustDefine: " + err.Error())
+	}
+	return f
+}
+
+// Start runs the OnEnter hook of the state the FSM is in, which is the initial state unless it was moved with SetState.
+// This gives the first state a chance to set up before any input arrives, which Define alone doesn't.
+// It also arms the timers of states with a Timeout, which never fire before Start.
+// Calling Start more than once is a no-op.
+// Spin doesn't require Start to have been called.
+// This method is thread-safe.
+func (f *FSM) Start(ctx context.Context) (context.Context, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.started {
+		return ctx, nil
+	}
+
+	s, ok := f.states[f.current]
+	if !ok {
+		return ctx, ImpossibleStateError(f.current)
+	}
+
+	f.log.Tracef("FSM: start in state [%d][%s]", f.current, f.getStateName(f.current))
+	f.started = true
+	if s.OnEnter != nil {
+		ctx = s.OnEnter(ctx)
+	}
+	f.armTimeout()
+	return ctx, nil
+}
+
 // Spin the FSM one time.
+// The context is checked before every hop of an action chain, and an InterruptedError is returned once it is done.
+// NO_INPUT and HALT only end an action chain: passing them to Spin is a mistake, reported with a NoInputError.
+// An Outcome leading to a state which isn't part of the definition, e.g. one picked by a Chooser,
+// makes Spin return an ImpossibleStateError before the hop is run.
+//
+// An input may match several Outcomes, which are tried in this order:
+// the Outcome for the exact input, then the ANY_INPUT Outcome, then the default outcome.
+// An Outcome whose Guard rejects the input is skipped in favour of the next one,
+// and a GuardRejectedError is only returned if a Guard rejected it and no later Outcome matched.
+//
+// While the FSM is in a state with a Child machine which hasn't reached a terminal state,
+// inputs are spun on the child first.
+// Inputs the child has no Outcome for fall through to the state's own Outcomes,
+// while any other error from the child is returned as is.
+// Once the child reaches a terminal state, the parent chains CHILD_DONE if the state has an Outcome for it,
+// and further inputs go straight to the parent.
+// The child is reset to its initial state every time the parent enters the composite state.
+//
+// Each hop runs in a fixed order: OnExit of the current state, the action, the callback set by
+// OnBeforeStateChange, the state change itself, OnEnter of the new state, and finally subscribers are notified.
+// The current state only changes once the action has completed, and the input it returns,
+// if any, is processed from the new state. If the action fails, the FSM stays where it was,
+// unless an error state was set with SetErrorState.
 // This method is thread-safe.
 func (f *FSM) Spin(ctx context.Context, in Input) (context.Context, error) {
 	f.Lock()
 	defer f.Unlock()
 
-	f.log.Tracef("FSM: get spin input [%d][%s]", in, f.getInputName(in))
+	ctx, _, err := f.spin(ctx, in, false)
+	return ctx, err
+}
+
+// Step spins the FSM with a background context, for callers that don't use contexts at all.
+// Actions receive context.Background(), so any that rely on context values won't find them,
+// and the context they return is discarded. Use Spin when the context matters.
+// This method is thread-safe.
+func (f *FSM) Step(in Input) error {
+	_, err := f.Spin(context.Background(), in)
+	return err
+}
+
+// SpinVerbose spins the FSM like Spin, and also returns every hop taken through the action chain, in order.
+// The hops taken before an error are returned along with it.
+// This method is thread-safe.
+func (f *FSM) SpinVerbose(ctx context.Context, in Input) (context.Context, []Transition, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.spin(ctx, in, true)
+}
+
+// SpinAll spins the FSM with each input in turn, stopping at the first error.
+// It returns the number of inputs which were applied successfully.
+// The lock is held for the whole batch, so no other Spin can interleave with it.
+// This method is thread-safe.
+func (f *FSM) SpinAll(ctx context.Context, inputs ...Input) (context.Context, int, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	for n, in := range inputs {
+		var err error
+		if ctx, _, err = f.spin(ctx, in, false); err != nil {
+			return ctx, n, err
+		}
+	}
+	return ctx, len(inputs), nil
+}
+
+// SpinUntil repeatedly calls next with the current state and spins the FSM with the input it returns,
+// until next returns false or Spin returns an error, which is returned right away.
+// Unlike SpinAll the lock isn't held between inputs, so next may use the FSM, but other spins may interleave.
+// This method is thread-safe.
+func (f *FSM) SpinUntil(ctx context.Context, next func(current int) (Input, bool)) (context.Context, error) {
+	for {
+		in, ok := next(f.Current())
+		if !ok {
+			return ctx, nil
+		}
+		var err error
+		if ctx, err = f.Spin(ctx, in); err != nil {
+			return ctx, err
+		}
+	}
+}
+
+// SpinIfChanged spins the FSM like Spin, unless the input would do nothing:
+// if the Outcome Peek finds for it stays in the current state and runs NO_ACTION, it is skipped.
+// It returns whether the input was spun.
+// A skipped input doesn't run its Guard, so it is skipped even if the Guard would have rejected it,
+// nor the OnExit and OnEnter hooks a self-transition would run.
+// Inputs for a state whose Child machine isn't terminal are always spun, since the child may handle them.
+// The check and the Spin happen under the same lock.
+// This method is thread-safe.
+func (f *FSM) SpinIfChanged(ctx context.Context, in Input) (context.Context, bool, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if s, ok := f.states[f.current]; ok && !f.paused && (s.Child == nil || s.Child.IsTerminal()) {
+		if do, _, ok := f.lookupOutcome(s, in); ok && do.target(f.current) == f.current && !do.hasAction() {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, in).Tracef("FSM: skip unchanging input [%d][%s]", in, f.getInputName(in))
+			}
+			f.lastChanged = false
+			return ctx, false, nil
+		}
+	}
+
+	ctx, _, err := f.spin(ctx, in, false)
+	return ctx, true, err
+}
+
+// SpinOverride spins the FSM like Spin, but uses override instead of the Outcome defined for the input
+// on the first hop, without changing the definition. Any input chained by its action is then processed normally.
+// The first hop skips the Child machine of a composite state, and the override's Guard is evaluated like any other.
+// This is meant for fault-injection tests, e.g. to make an action fail or redirect a transition.
+// Will return an UndefinedTargetError if the target of override isn't part of the FSM definition,
+// an InvalidWeightError if its Weights can't be drawn from, as well as any error Spin would return.
+// This method is thread-safe.
+func (f *FSM) SpinOverride(ctx context.Context, in Input, override Outcome) (context.Context, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	exists := func(index int) bool { return hasState(f.states, index) }
+	if err := checkTarget(f.current, in, override, exists); err != nil {
+		return ctx, err
+	}
+
+	f.override = &override
+	defer func() { f.override = nil }()
+
+	ctx, _, err := f.spin(ctx, in, false)
+	return ctx, err
+}
+
+// Peek returns the state the FSM would move to for an input, without running any actions or changing state.
+// It returns the same InvalidInputError or ImpossibleStateError that Spin would.
+// Peek only looks at a single hop: it can't follow chained inputs, since those are returned by actions.
+// Guards and Choosers aren't evaluated either, as they may depend on the context or have side effects,
+// so Peek returns CHOICE for Outcomes whose target is picked at spin time.
+// This method is thread-safe.
+func (f *FSM) Peek(in Input) (int, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	s, ok := f.states[f.current]
+	if !ok {
+		return f.current, ImpossibleStateError(f.current)
+	}
+
+	do, _, ok := f.lookupOutcome(s, in)
+	if !ok {
+		return f.current, InvalidInputError{f.current, in, 1, in, EXTERNAL_INPUT}
+	}
+	return do.target(f.current), nil
+}
+
+// Outcome returns the Outcome the definition lists for an input in a state, and whether there is one.
+// The lookup is exact: ANY_INPUT and the default outcome aren't consulted, so explicit transitions can be told
+// apart from fallbacks. ANY_INPUT itself can be looked up, as it is listed like any other input.
+// The Outcome carries its Meta labels, which must not be modified.
+// This method is thread-safe.
+func (f *FSM) Outcome(state int, in Input) (Outcome, bool) {
+	f.RLock()
+	defer f.RUnlock()
+
+	do, ok := f.states[state].Outcomes[in]
+	return do, ok
+}
+
+// Accepts reports whether the current state has an Outcome for the input, exact, ANY_INPUT or default,
+// without running anything. Guards aren't evaluated, since they may have side effects: see AcceptsWithGuards.
+// A Child machine which could handle the input isn't consulted.
+// This method is thread-safe.
+func (f *FSM) Accepts(in Input) bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	s, ok := f.states[f.current]
+	if !ok || in == NO_INPUT || in == HALT {
+		return false
+	}
+	_, _, ok = f.lookupOutcome(s, in)
+	return ok
+}
+
+// AcceptsWithGuards reports whether the input would be accepted like Accepts,
+// also requiring the Guard of the Outcome Spin would pick to allow it.
+// The Guards are called with ctx, so any side effects they have happen.
+// This method is thread-safe.
+func (f *FSM) AcceptsWithGuards(ctx context.Context, in Input) bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	s, ok := f.states[f.current]
+	if !ok || in == NO_INPUT || in == HALT {
+		return false
+	}
+	_, _, _, ok = f.selectOutcome(ctx, s, in)
+	return ok
+}
 
-	for i := in; i != NO_INPUT; {
+// spin runs an input and any chained inputs through the FSM, returning the hops taken if record is set,
+// and logs the errors which shouldn't go unnoticed: see SetErrorLogLevel.
+// The caller must hold the lock.
+func (f *FSM) spin(ctx context.Context, in Input, record bool) (context.Context, []Transition, error) {
+	ctx, hops, err := f.spinChain(ctx, in, record)
+	if err != nil {
+		f.logError(in, err)
+	}
+	return ctx, hops, err
+}
+
+// spinChain runs an input and any chained inputs through the FSM, returning the hops taken if record is set.
+// Hops aren't collected otherwise, so a plain Spin doesn't allocate for them.
+// The caller must hold the lock.
+func (f *FSM) spinChain(ctx context.Context, in Input, record bool) (_ context.Context, hops []Transition, err error) {
+	var recovered bool
+	var taken int
+	var routed error
 
-		f.log.Tracef("FSM: process input [%d][%s]", i, f.getInputName(i))
+	f.lastChanged = false
+	defer func(start int) { f.lastChanged = f.current != start }(f.current)
+	if f.atomic {
+		defer f.endAtomic(f.beginAtomic(), &err, &routed)
+	}
+
+	if f.spinObserver != nil {
+		start, began := f.current, time.Now()
+		defer func() { f.spinObserver(start, in, taken, time.Since(began)) }()
+	}
+
+	if f.logs(logrus.TraceLevel) {
+		f.logEntry(f.current, in).Tracef("FSM: get spin input [%d][%s]", in, f.getInputName(in))
+	}
+
+	if f.paused {
+		if f.logs(logrus.TraceLevel) {
+			f.logEntry(f.current, in).Tracef("FSM: paused, rejected input [%d][%s]", in, f.getInputName(in))
+		}
+		return ctx, hops, PausedError{f.current, in}
+	}
+
+	if in == NO_INPUT || in == HALT {
+		if f.logs(logrus.TraceLevel) {
+			f.logEntry(f.current, in).Tracef("FSM: no input given")
+		}
+		return ctx, hops, NoInputError{f.current}
+	}
+
+	src := EXTERNAL_INPUT
+	for i, depth := in, 1; i != NO_INPUT && i != HALT; depth++ {
+
+		if f.maxDepth > 0 && depth > f.maxDepth {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).Tracef("FSM: chain limit exceeded [%d] at input [%d][%s]", f.maxDepth, i, f.getInputName(i))
+			}
+			return ctx, hops, ChainLimitExceededError{f.maxDepth, i}
+		}
+
+		if err := ctx.Err(); err != nil {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).WithError(err).Tracef("FSM: interrupted before input [%d][%s]: %v", i, f.getInputName(i), err)
+			}
+			return ctx, hops, InterruptedError{f.current, i, err}
+		}
+
+		if f.logs(logrus.TraceLevel) {
+			f.logEntry(f.current, i).Tracef("FSM: process input [%d][%s]", i, f.getInputName(i))
+		}
 
 		s, ok := f.states[f.current]
+		if !ok && f.policy == PolicyResetToInitial && !recovered {
+			if f.logs(logrus.WarnLevel) {
+				f.logEntry(f.current, i).Warnf("FSM: invalid state [%d], reset to initial state [%d][%s]", f.current, f.initial, f.getStateName(f.initial))
+			}
+			f.current = f.initial
+			recovered = true
+			continue
+		}
 		if !ok {
-			f.log.Tracef("FSM: invalid state [%d]", f.current)
-			return ctx, ImpossibleStateError(f.current)
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).Tracef("FSM: invalid state [%d]", f.current)
+			}
+			return ctx, hops, ImpossibleStateError(f.current)
 		}
 
-		do, ok := s.Outcomes[i]
+		if s.Child != nil && f.override == nil {
+			var handled bool
+			var err error
+			ctx, i, handled, err = f.delegate(ctx, s, i)
+			if err != nil {
+				return ctx, hops, err
+			}
+			if handled {
+				continue
+			}
+		}
+
+		do, key, rejected, ok := f.selectOutcome(ctx, s, i)
+		if !ok && rejected {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).Tracef("FSM: input [%d][%s] rejected by guard in current state [%d][%s]", i, f.getInputName(i), f.current, f.getStateName(f.current))
+			}
+			return ctx, hops, GuardRejectedError{f.current, i}
+		}
 		if !ok {
-			f.log.Tracef("FSM: invalid input [%d][%s] in current state [%d][%s]", i, f.getInputName(i), f.current, f.getStateName(f.current))
-			return ctx, InvalidInputError{f.current, i}
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).Tracef("FSM: invalid input [%d][%s] in current state [%d][%s]", i, f.getInputName(i), f.current, f.getStateName(f.current))
+			}
+			if f.onReject != nil {
+				f.onReject(f.current, i)
+			}
+			return ctx, hops, InvalidInputError{f.current, i, depth, in, src}
 		}
 
-		ctx, i = do.Action(ctx)
+		do.State = do.target(f.current)
+		if do.State == CHOICE {
+			var target int
+			var err error
+			if do.Chooser != nil {
+				target = do.Chooser(ctx)
+			} else if target, err = f.pickWeighted(f.current, i, do.Weights); err != nil {
+				if f.logs(logrus.TraceLevel) {
+					f.logEntry(f.current, i).WithError(err).Tracef("FSM: invalid weights in current state [%d][%s]", f.current, f.getStateName(f.current))
+				}
+				return ctx, hops, err
+			}
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).Tracef("FSM: choice picked state [%d]", target)
+			}
+			do.State = target
+		}
+		if _, ok := f.states[do.State]; !ok {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(f.current, i).Tracef("FSM: outcome targets invalid state [%d]", do.State)
+			}
+			return ctx, hops, ImpossibleStateError(do.State)
+		}
+
+		internal := do.Internal && do.State == f.current
+		if s.OnExit != nil && !internal {
+			ctx = s.OnExit(ctx)
+		}
+
+		from, hop := f.current, i
+		if f.coverage != nil {
+			f.coverage.record(from, key)
+		}
+
+		var err error
+		if ctx, i, err = f.runAction(ctx, hop, do); err != nil {
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(from, hop).WithError(err).Tracef("FSM: action failed in state [%d][%s] on input [%d][%s]: %v", from, f.getStateName(from), hop, f.getInputName(hop), err)
+			}
+			if _, ok := err.(ActionTimeoutError); !ok {
+				err = ActionError{from, hop, err}
+			}
+			es, ok := f.routeError(from)
+			if !ok {
+				return ctx, hops, err
+			}
+
+			if f.logs(logrus.TraceLevel) {
+				f.logEntry(from, hop).Tracef("FSM: route failed action to error state [%d][%s]", es.Index, f.getStateName(es.Index))
+			}
+			if internal && s.OnExit != nil {
+				ctx = s.OnExit(ctx)
+			}
+			ctx = context.WithValue(ctx, routedErrorKey{}, err)
+			do.State, i, internal = es.Index, NO_INPUT, false
+			if f.errorRouting == ErrorRoutingReturn {
+				routed = err
+			} else if _, ok := es.Outcomes[ACTION_FAILED]; ok {
+				i = ACTION_FAILED
+			}
+		}
+		src = ACTION_INPUT
+		if f.beforeChange != nil {
+			f.beforeChange(from, do.State)
+		}
+		if !internal {
+			f.pushUndo(from)
+		}
 		f.current = do.State
-		f.log.Tracef("FSM: set current state [%d][%s] with next input [%d][%s]", f.current, f.getStateName(f.current), i, f.getInputName(i))
+		if f.pending == nil {
+			f.checkDone()
+		}
+		f.visit()
+
+		if next, ok := f.states[f.current]; ok && !internal {
+			if next.Child != nil {
+				next.Child.Reset()
+			}
+			if next.OnEnter != nil {
+				ctx = next.OnEnter(ctx)
+			}
+		}
+		if !internal {
+			f.armTimeout()
+		}
+
+		t := Transition{From: from, To: f.current, Input: hop, Time: time.Now(), Halted: i == HALT}
+		taken++
+		if record {
+			hops = append(hops, t)
+		}
+		if !internal {
+			f.notify(t)
+		}
+		if f.logs(logrus.TraceLevel) {
+			f.logEntry(from, hop).WithField("next_state", f.current).Tracef("FSM: set current state [%d][%s] with next input [%d][%s]", f.current, f.getStateName(f.current), i, f.getInputName(i))
+		}
+		if i == HALT && f.logs(logrus.TraceLevel) {
+			f.logEntry(f.current, hop).Tracef("FSM: chain halted by action in state [%d][%s]", f.current, f.getStateName(f.current))
+		}
 	}
 
-	return ctx, nil
+	return ctx, hops, routed
 }
 
-// Set logger with description states and inputs strings
-func (f *FSM) SetLogger(logger *logrus.Logger, states map[int]string, inputs map[Input]string) {
-	if logger != nil {
+// Current returns the index of the state the FSM is currently in.
+// This method is thread-safe.
+func (f *FSM) Current() int {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.current
+}
+
+// LastChanged reports whether the most recent Spin left the FSM in a different state than it started in.
+// A chain which loops back to its starting state counts as unchanged, while a chain which failed partway
+// counts as changed if it got somewhere else before failing.
+// Every way of spinning the FSM updates it, including spins rejected before any hop.
+// This method is thread-safe.
+func (f *FSM) LastChanged() bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.lastChanged
+}
+
+// InitialState returns the index of the state the FSM started in, which Reset returns to.
+// This method is thread-safe.
+func (f *FSM) InitialState() int {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.initial
+}
+
+// CurrentName returns the name of the current state as set by SetLogger,
+// or the empty string if no name is known.
+// This method is thread-safe.
+func (f *FSM) CurrentName() string {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.getStateName(f.current)
+}
+
+// Names returns copies of the name maps set by SetLogger or by a JSON or YAML definition,
+// in the form the "state_names" and "input_names" of a definition take, so they can be stored along with it.
+// A map which was never set is returned as nil. Names computed by SetNamedTypes functions aren't included.
+// This method is thread-safe.
+func (f *FSM) Names() (states map[int]string, inputs map[Input]string) {
+	f.RLock()
+	defer f.RUnlock()
+
+	if f.stateNames != nil {
+		states = make(map[int]string, len(f.stateNames))
+		for k, v := range f.stateNames {
+			states[k] = v
+		}
+	}
+	if f.inputNames != nil {
+		inputs = make(map[Input]string, len(f.inputNames))
+		for k, v := range f.inputNames {
+			inputs[k] = v
+		}
+	}
+	return states, inputs
+}
+
+// ValidInputs returns the inputs the current state has an Outcome for, sorted ascending.
+// The ANY_INPUT wildcard isn't included, since it can't be passed to Spin itself.
+// This method is thread-safe.
+func (f *FSM) ValidInputs() []Input {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.validInputs()
+}
+
+// ValidInputNames returns the names of the inputs returned by ValidInputs, in the same order.
+// Inputs without a name set by SetLogger are returned as the empty string.
+// This method is thread-safe.
+func (f *FSM) ValidInputNames() []string {
+	f.RLock()
+	defer f.RUnlock()
+
+	inputs := f.validInputs()
+	names := make([]string, len(inputs))
+	for i, in := range inputs {
+		names[i] = f.getInputName(in)
+	}
+	return names
+}
+
+func (f *FSM) validInputs() []Input {
+	inputs := []Input{}
+	for _, in := range sortedInputs(f.states[f.current].Outcomes) {
+		if in != ANY_INPUT {
+			inputs = append(inputs, in)
+		}
+	}
+	return inputs
+}
+
+// SetState forces the FSM into the given state without spinning.
+// No actions or hooks are run.
+// Will return an ImpossibleStateError if the state isn't part of the FSM definition.
+// This method is thread-safe.
+func (f *FSM) SetState(s int) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.states[s]; !ok {
+		return ImpossibleStateError(s)
+	}
+
+	f.log.Tracef("FSM: force current state [%d][%s]", s, f.getStateName(s))
+	f.current = s
+	f.armTimeout()
+	return nil
+}
+
+// Pause makes Spin reject every input with a PausedError, leaving the FSM where it is, until Resume is called.
+// A Spin already running when Pause is called completes first.
+// This method is thread-safe.
+func (f *FSM) Pause() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.log.Tracef("FSM: pause in state [%d][%s]", f.current, f.getStateName(f.current))
+	f.paused = true
+}
+
+// Resume lets Spin process inputs again after Pause.
+// This method is thread-safe.
+func (f *FSM) Resume() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.log.Tracef("FSM: resume in state [%d][%s]", f.current, f.getStateName(f.current))
+	f.paused = false
+}
+
+// IsPaused reports whether the FSM is paused.
+// This method is thread-safe.
+func (f *FSM) IsPaused() bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.paused
+}
+
+// SetMaxChainDepth limits the number of hops a single Spin may take through chained actions.
+// Spin returns a ChainLimitExceededError once the limit is passed.
+// A value of zero or less removes the limit.
+// This method is thread-safe.
+func (f *FSM) SetMaxChainDepth(n int) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.maxDepth = n
+}
+
+// SetImpossibleStatePolicy sets what Spin does when the FSM is found in a state which isn't part of its definition.
+// Under PolicyResetToInitial, the FSM is moved to its initial state without running any hooks,
+// and the input being processed is retried there. This happens at most once per Spin,
+// so if the FSM ends up in an impossible state again during the same Spin, an ImpossibleStateError is returned.
+// This method is thread-safe.
+func (f *FSM) SetImpossibleStatePolicy(p Policy) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.policy = p
+}
+
+// SetDefaultOutcome sets an Outcome used for any input the current state has no Outcome for,
+// neither exact nor ANY_INPUT, instead of returning an InvalidInputError.
+// Will return an UndefinedTargetError if the target of o isn't part of the FSM definition,
+// or an InvalidWeightError if its Weights can't be drawn from, reported for the current state and NO_INPUT.
+// The default outcome is left unchanged in that case.
+// This method is thread-safe.
+func (f *FSM) SetDefaultOutcome(o Outcome) error {
+	f.Lock()
+	defer f.Unlock()
+
+	exists := func(index int) bool { return hasState(f.states, index) }
+	if err := checkTarget(f.current, NO_INPUT, o, exists); err != nil {
+		return err
+	}
+
+	f.fallback = &o
+	return nil
+}
+
+// Reset moves the FSM back to the state it was in when it was defined, and forgets the states Undo could return to.
+// If the FSM was done, a new Done channel is handed out from then on.
+// No actions or hooks are run.
+// This method is thread-safe.
+func (f *FSM) Reset() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.reset()
+}
+
+// reset moves the FSM back to its initial state.
+// The caller must hold the lock.
+func (f *FSM) reset() {
+	f.log.Tracef("FSM: reset to initial state [%d][%s]", f.initial, f.getStateName(f.initial))
+	f.current = f.initial
+	f.undo = f.undo[:0]
+	f.reopenDone()
+	f.armTimeout()
+}
+
+// Clone returns an independent copy of the FSM, positioned at its initial state.
+// The definition, Child machines, name maps, tags, final states and settings are copied,
+// including the sizes set by EnableHistory and EnableUndo, and callbacks and the logger are shared.
+// Subscribers, the history, the undo stack, coverage, the pause and the random source set by SetRand
+// aren't carried over, and visited states start over from the initial state.
+// To continue from the same position, call SetState on the clone.
+// This method is thread-safe.
+func (f *FSM) Clone() *FSM {
+	f.RLock()
+	defer f.RUnlock()
+
+	states := make(map[int]State, len(f.states))
+	for index, s := range f.states {
+		outcomes := make(map[Input]Outcome, len(s.Outcomes))
+		for in, do := range s.Outcomes {
+			if do.Meta != nil {
+				meta := make(map[string]string, len(do.Meta))
+				for k, v := range do.Meta {
+					meta[k] = v
+				}
+				do.Meta = meta
+			}
+			if do.Weights != nil {
+				do.Weights = append([]WeightedTarget(nil), do.Weights...)
+			}
+			outcomes[in] = do
+		}
+		s.Outcomes = outcomes
+		if s.Child != nil {
+			s.Child = s.Child.Clone()
+		}
+		states[index] = s
+	}
+
+	var stateNames map[int]string
+	if f.stateNames != nil {
+		stateNames = make(map[int]string, len(f.stateNames))
+		for k, v := range f.stateNames {
+			stateNames[k] = v
+		}
+	}
+
+	var inputNames map[Input]string
+	if f.inputNames != nil {
+		inputNames = make(map[Input]string, len(f.inputNames))
+		for k, v := range f.inputNames {
+			inputNames[k] = v
+		}
+	}
+
+	var fallback *Outcome
+	if f.fallback != nil {
+		o := *f.fallback
+		fallback = &o
+	}
+
+	var tags map[int]map[string]bool
+	if f.tags != nil {
+		tags = make(map[int]map[string]bool, len(f.tags))
+		for index, set := range f.tags {
+			tags[index] = make(map[string]bool, len(set))
+			for tag := range set {
+				tags[index][tag] = true
+			}
+		}
+	}
+
+	var final map[int]bool
+	if f.final != nil {
+		final = make(map[int]bool, len(f.final))
+		for index := range f.final {
+			final[index] = true
+		}
+	}
+
+	// Visits start over from the initial state, but are still tracked for OnFirstVisit.
+	var visited map[int]bool
+	if f.visited != nil {
+		visited = map[int]bool{f.initial: true}
+	}
+
+	var errorState *int
+	if f.errorState != nil {
+		index := *f.errorState
+		errorState = &index
+	}
+
+	return &FSM{
+		states:        states,
+		initial:       f.initial,
+		current:       f.initial,
+		maxDepth:      f.maxDepth,
+		historyLen:    f.historyLen,
+		undoLen:       f.undoLen,
+		final:         final,
+		fallback:      fallback,
+		errorState:    errorState,
+		tags:          tags,
+		errorRouting:  f.errorRouting,
+		errorLevel:    f.errorLevel,
+		atomic:        f.atomic,
+		actionTimeout: f.actionTimeout,
+		metrics:       f.metrics,
+		spinObserver:  f.spinObserver,
+		onReject:      f.onReject,
+		beforeChange:  f.beforeChange,
+		onFirstVisit:  f.onFirstVisit,
+		visited:       visited,
+		policy:        f.policy,
+		warnChecks:    f.warnChecks,
+		recoverFn:     f.recoverFn,
+		middleware:    append([]Middleware(nil), f.middleware...),
+		log:           f.log,
+		stateNames:    stateNames,
+		inputNames:    inputNames,
+		stateNamer:    f.stateNamer,
+		inputNamer:    f.inputNamer,
+	}
+}
+
+// Set logger with description states and inputs strings.
+// The logger may be a *logrus.Logger or a *logrus.Entry already carrying contextual fields.
+// A nil logger keeps the current one. Until a logger is set, the FSM logs nothing.
+// This method is thread-safe.
+func (f *FSM) SetLogger(logger logrus.Ext1FieldLogger, states map[int]string, inputs map[Input]string) {
+	f.Lock()
+	defer f.Unlock()
+
+	if l, ok := logger.(*logrus.Logger); logger != nil && !(ok && l == nil) {
 		f.log = logger
 	}
 	f.stateNames = states
 	f.inputNames = inputs
 }
 
+// SetErrorLogLevel sets the level at which Spin logs the InvalidInputError and ImpossibleStateError it returns,
+// on top of the trace logs of every hop, so they show up without enabling tracing.
+// By default invalid inputs are logged as warnings, and impossible states as errors.
+// Use logrus.TraceLevel to keep them with the trace logs.
+// The errors are logged through the logger set by SetLogger, whose own level still applies,
+// so nothing is logged with the default logger.
+// Errors of a Child machine which fall through to the parent state aren't logged by the child.
+// This method is thread-safe.
+func (f *FSM) SetErrorLogLevel(l logrus.Level) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.errorLevel = &l
+}
+
+// logError logs an error returned by Spin, if it is one the caller should hear about.
+// The caller must hold the lock.
+func (f *FSM) logError(in Input, err error) {
+	var level logrus.Level
+	switch err.(type) {
+	case InvalidInputError:
+		level = logrus.WarnLevel
+	case ImpossibleStateError:
+		level = logrus.ErrorLevel
+	default:
+		return
+	}
+	if f.errorLevel != nil {
+		level = *f.errorLevel
+	}
+	if f.logs(level) {
+		f.logEntry(f.current, in).WithError(err).Logf(level, "FSM: spin input [%d][%s] failed: %v", in, f.getInputName(in), err)
+	}
+}
+
+// SetNamedTypes sets functions computing the names of states and inputs, such as the String methods of enum types,
+// instead of listing every name in the maps given to SetLogger.
+// When a function is set it takes precedence over the corresponding map, and a nil function falls back to it.
+// This method is thread-safe.
+func (f *FSM) SetNamedTypes(stateNamer func(int) string, inputNamer func(Input) string) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.stateNamer = stateNamer
+	f.inputNamer = inputNamer
+}
+
+// target returns the State of the Outcome, resolving SAME_STATE to the state from which it fires.
+func (o Outcome) target(from int) int {
+	if o.State == SAME_STATE {
+		return from
+	}
+	return o.State
+}
+
+// run returns the action to perform for the Outcome.
+func (o Outcome) run() ActionE {
+	switch {
+	case o.ActionE != nil:
+		return o.ActionE
+	case o.Action != nil:
+		return WrapAction(o.Action)
+	default:
+		return WrapAction(NO_ACTION)
+	}
+}
+
+// lookupOutcome finds the Outcome for an input in a state,
+// falling back to the ANY_INPUT wildcard and then the FSM's default outcome.
+// It also returns the key the Outcome was found under, which is NO_INPUT for the default outcome.
+func (f *FSM) lookupOutcome(s State, in Input) (Outcome, Input, bool) {
+	if do, ok := s.Outcomes[in]; ok {
+		return do, in, true
+	}
+	if do, ok := s.Outcomes[ANY_INPUT]; ok {
+		return do, ANY_INPUT, true
+	}
+	if f.fallback != nil {
+		return *f.fallback, NO_INPUT, true
+	}
+	return Outcome{}, NO_INPUT, false
+}
+
+// selectOutcome finds the Outcome to fire for an input in a state, in the same order as lookupOutcome,
+// skipping Outcomes whose Guard rejects the input.
+// It also returns the key the Outcome was found under, and whether any Guard rejected the input.
+// A pending SpinOverride Outcome is used instead, once.
+func (f *FSM) selectOutcome(ctx context.Context, s State, in Input) (Outcome, Input, bool, bool) {
+	if f.override != nil {
+		do := *f.override
+		f.override = nil
+		if do.Guard != nil && !do.Guard(ctx) {
+			return Outcome{}, NO_INPUT, true, false
+		}
+		return do, in, false, true
+	}
+
+	rejected := false
+	for _, key := range [...]Input{in, ANY_INPUT} {
+		do, ok := s.Outcomes[key]
+		if !ok {
+			continue
+		}
+		if do.Guard != nil && !do.Guard(ctx) {
+			rejected = true
+			continue
+		}
+		return do, key, rejected, true
+	}
+	if f.fallback != nil {
+		if f.fallback.Guard == nil || f.fallback.Guard(ctx) {
+			return *f.fallback, NO_INPUT, rejected, true
+		}
+		rejected = true
+	}
+	return Outcome{}, NO_INPUT, rejected, false
+}
+
+// sortedStates returns the indices of all defined states in ascending order.
+func (f *FSM) sortedStates() []int {
+	return sortedIndices(f.states)
+}
+
+// sortedIndices returns the indices of a state map in ascending order.
+func sortedIndices(states map[int]State) []int {
+	indices := make([]int, 0, len(states))
+	for index := range states {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// sortedInputs returns the inputs of an outcome map in ascending order.
+func sortedInputs(outcomes map[Input]Outcome) []Input {
+	inputs := make([]Input, 0, len(outcomes))
+	for in := range outcomes {
+		inputs = append(inputs, in)
+	}
+	sort.Slice(inputs, func(a, b int) bool { return inputs[a] < inputs[b] })
+	return inputs
+}
+
+// hasState reports whether index is one of the given states.
+func hasState(states map[int]State, index int) bool {
+	_, ok := states[index]
+	return ok
+}
+
+// logs reports whether the logger emits messages at the level.
+// Log entries are only built when it does, so spinning with logging off doesn't allocate.
+func (f *FSM) logs(level logrus.Level) bool {
+	switch l := f.log.(type) {
+	case *logrus.Logger:
+		return l.IsLevelEnabled(level)
+	case *logrus.Entry:
+		return l.Logger.IsLevelEnabled(level)
+	}
+	return true
+}
+
+// logEntry returns a log entry carrying a state and an input as structured fields.
+// Check that the level is enabled with logs first, as the entry and names are built right away.
+func (f *FSM) logEntry(state int, in Input) *logrus.Entry {
+	return f.log.WithFields(logrus.Fields{
+		"state":      state,
+		"state_name": f.getStateName(state),
+		"input":      int(in),
+		"input_name": f.getInputName(in),
+	})
+}
+
 func (f *FSM) getInputName(input Input) string {
+	if f.inputNamer != nil {
+		return f.inputNamer(input)
+	}
 	name, ok := f.inputNames[input]
 	if !ok {
 		return ""
@@ -146,6 +1414,9 @@ func (f *FSM) getInputName(input Input) string {
 }
 
 func (f *FSM) getStateName(state int) string {
+	if f.stateNamer != nil {
+		return f.stateNamer(state)
+	}
 	name, ok := f.stateNames[state]
 	if !ok {
 		return ""
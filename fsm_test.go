@@ -1,8 +1,16 @@
 package fsm
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -37,25 +45,25 @@ func TestStates(t *testing.T) {
 	state1 := State{
 		Index: test_state_1,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2, NO_ACTION},
-			test_input_2: Outcome{test_state_3, NO_ACTION},
-			test_input_3: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 	state2 := State{
 		Index: test_state_2,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_1, NO_ACTION},
-			test_input_2: Outcome{test_state_1, NO_ACTION},
-			test_input_3: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 	state3 := State{
 		Index: test_state_3,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2, NO_ACTION},
-			test_input_2: Outcome{test_state_1, NO_ACTION},
-			test_input_3: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 
@@ -93,28 +101,28 @@ func TestChain(t *testing.T) {
 	state1 := State{
 		Index: test_state_1,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2,
-				func(ctx context.Context) (context.Context, Input) { func1_hit = true; return ctx, test_input_2 }},
-			test_input_2: Outcome{test_state_3,
-				func(ctx context.Context) (context.Context, Input) { func3_hit = true; return ctx, NO_INPUT }},
-			test_input_3: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { func1_hit = true; return ctx, test_input_2 }},
+			test_input_2: Outcome{State: test_state_3,
+				Action: func(ctx context.Context) (context.Context, Input) { func3_hit = true; return ctx, NO_INPUT }},
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 	state2 := State{
 		Index: test_state_2,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_1, NO_ACTION},
-			test_input_2: Outcome{test_state_1,
-				func(ctx context.Context) (context.Context, Input) { func2_hit = true; return ctx, test_input_2 }},
-			test_input_3: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1,
+				Action: func(ctx context.Context) (context.Context, Input) { func2_hit = true; return ctx, test_input_2 }},
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 	state3 := State{
 		Index: test_state_3,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2, NO_ACTION},
-			test_input_2: Outcome{test_state_1, NO_ACTION},
-			test_input_3: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 
@@ -137,15 +145,15 @@ func TestImpossibleState(t *testing.T) {
 	state1 := State{
 		Index: test_state_1,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2, NO_ACTION},
-			test_input_2: Outcome{test_state_3, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
 		},
 	}
 	state2 := State{
 		Index: test_state_2,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_1, NO_ACTION},
-			test_input_2: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 
@@ -168,21 +176,71 @@ func TestImpossibleState(t *testing.T) {
 	}
 }
 
+func TestImpossibleStatePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	// Once armed, entering state 2 moves the FSM out of its definition.
+	var corrupt *int
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+		OnEnter: func(ctx context.Context) context.Context {
+			if corrupt != nil {
+				*corrupt = test_state_3
+			}
+			return ctx
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetImpossibleStatePolicy(PolicyResetToInitial)
+
+	// The input is retried from the initial state.
+	fsm.current = test_state_3
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	// The reset is only tried once per spin.
+	if err := fsm.SetDefaultOutcome(Outcome{State: test_state_2,
+		Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_1 }}); err != nil {
+		t.Fatal("Failed to set default outcome: ", err)
+	}
+	corrupt = &fsm.current
+	fsm.current = test_state_3
+	_, err = fsm.Spin(ctx, test_input_2)
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
 func TestInvalidInput(t *testing.T) {
 	ctx := context.Background()
 
 	state1 := State{
 		Index: test_state_1,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2, NO_ACTION},
-			test_input_2: Outcome{test_state_3, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
 		},
 	}
 	state2 := State{
 		Index: test_state_2,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_1, NO_ACTION},
-			test_input_2: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 
@@ -196,12 +254,46 @@ func TestInvalidInput(t *testing.T) {
 	if err == nil {
 		t.Fatalf("FSM didn't error when spun with invalid input.")
 	}
-	switch err.(type) {
+	switch e := err.(type) {
 	case InvalidInputError:
+		if e.Source != EXTERNAL_INPUT {
+			t.Errorf("Wrong input source. (Expected: %v, Got: %v)", EXTERNAL_INPUT, e.Source)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+// Test that we error if NO_INPUT is passed to Spin instead of silently doing nothing.
+func TestNoInput(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	_, err = fsm.Spin(ctx, NO_INPUT)
+	switch err.(type) {
+	case NoInputError:
 		t.Logf("FSM corrently returned error: %v", err.Error())
 	default:
 		t.Fatalf("FSM returned wrong error type: %T", err)
 	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("FSM moved on NO_INPUT. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
 }
 
 // Test that we error if you try to create an FSM with clashing states.
@@ -210,15 +302,15 @@ func TestStateClash(t *testing.T) {
 	state1 := State{
 		Index: test_state_1,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_2, NO_ACTION},
-			test_input_2: Outcome{test_state_3, NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
 		},
 	}
 	state2 := State{
 		Index: test_state_1,
 		Outcomes: map[Input]Outcome{
-			test_input_1: Outcome{test_state_1, NO_ACTION},
-			test_input_2: Outcome{test_state_1, NO_ACTION},
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
 		},
 	}
 
@@ -235,3 +327,1807 @@ func TestStateClash(t *testing.T) {
 		t.Fatalf("FSM returned wrong error type: %T", err)
 	}
 }
+
+// Test that we error instead of panicking if you try to create an FSM without states.
+func TestEmptyDefinition(t *testing.T) {
+	_, err := Define()
+
+	if err == nil {
+		t.Fatalf("Didn't error creating FSM without states.")
+	}
+	switch err.(type) {
+	case EmptyDefinitionError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	if _, err := NewBuilder().Build(); err != (EmptyDefinitionError{}) {
+		t.Errorf("Builder returned wrong error: %v", err)
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Wrong current state. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+	if fsm.CurrentName() != "" {
+		t.Errorf("Expected empty state name without name map, got %q", fsm.CurrentName())
+	}
+
+	fsm.SetLogger(nil, map[int]string{test_state_1: "one", test_state_2: "two"}, nil)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	if fsm.Current() != test_state_2 {
+		t.Errorf("Wrong current state. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+	if fsm.CurrentName() != "two" {
+		t.Errorf("Wrong current state name. (Expected: %q, Got: %q)", "two", fsm.CurrentName())
+	}
+}
+
+// Test that enter and exit hooks fire around every hop, including chained ones.
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	hook := func(name string) Hook {
+		return func(ctx context.Context) context.Context { calls = append(calls, name); return ctx }
+	}
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					calls = append(calls, "action")
+					return ctx, test_input_2
+				}},
+		},
+		OnEnter: hook("enter1"),
+		OnExit:  hook("exit1"),
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+		OnEnter: hook("enter2"),
+		OnExit:  hook("exit2"),
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	expected := []string{"exit1", "action", "enter2", "exit2", "enter2"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Wrong hook calls. (Expected: %v, Got: %v)", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("Wrong hook calls. (Expected: %v, Got: %v)", expected, calls)
+		}
+	}
+}
+
+func TestSetState(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if err := fsm.SetState(test_state_2); err != nil {
+		t.Fatal("Failed to set state: ", err)
+	}
+	if fsm.Current() != test_state_2 {
+		t.Errorf("Wrong current state. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+
+	// Setting an undefined state should fail and leave the FSM untouched.
+	err = fsm.SetState(test_state_3)
+	if err == nil {
+		t.Fatalf("FSM didn't error when set to an undefined state.")
+	}
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_2 {
+		t.Errorf("FSM state changed after failed SetState. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+}
+
+// Test that two actions feeding each other don't spin forever.
+func TestChainLimit(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_1 }},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetMaxChainDepth(10)
+
+	ctx, err = fsm.Spin(ctx, test_input_1)
+	if err == nil {
+		t.Fatalf("FSM didn't error on endless chain.")
+	}
+	switch e := err.(type) {
+	case ChainLimitExceededError:
+		if e.Depth != 10 {
+			t.Errorf("Wrong chain depth in error. (Expected: %v, Got: %v)", 10, e.Depth)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+// Test that cancelling the context stops a chain after the current hop.
+func TestCancelChain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { cancel(); return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	ctx, err = fsm.Spin(ctx, test_input_1)
+	if err == nil {
+		t.Fatalf("FSM didn't error when context was cancelled.")
+	}
+	switch e := err.(type) {
+	case InterruptedError:
+		if e.Err != context.Canceled {
+			t.Errorf("Wrong wrapped error. (Expected: %v, Got: %v)", context.Canceled, e.Err)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	// The first hop completed, so the FSM should have stopped in state 2.
+	if fsm.Current() != test_state_2 {
+		t.Errorf("FSM in wrong state. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+
+	allow := false
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{
+				State:  test_state_2,
+				Action: NO_ACTION,
+				Guard:  func(ctx context.Context) bool { return allow },
+			},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	ctx, err = fsm.Spin(ctx, test_input_1)
+	if err == nil {
+		t.Fatalf("FSM didn't error when guard rejected input.")
+	}
+	switch err.(type) {
+	case GuardRejectedError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("FSM moved despite guard rejection. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	allow = true
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+}
+
+// Test that a guard rejection falls through to the wildcard and then the default outcome.
+func TestGuardPriority(t *testing.T) {
+	ctx := context.Background()
+
+	allowExact, allowAny, allowDefault := true, true, true
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Guard: func(ctx context.Context) bool { return allowExact }},
+			ANY_INPUT:    Outcome{State: test_state_3, Guard: func(ctx context.Context) bool { return allowAny }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3, State{Index: 3})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetDefaultOutcome(Outcome{State: 3, Guard: func(ctx context.Context) bool { return allowDefault }})
+
+	tiers := []struct {
+		exact, any, def bool
+		expected        int
+	}{
+		{true, true, true, test_state_2},
+		{false, true, true, test_state_3},
+		{false, false, true, 3},
+	}
+	for _, tier := range tiers {
+		allowExact, allowAny, allowDefault = tier.exact, tier.any, tier.def
+		fsm.Reset()
+		assertState(t, ctx, fsm, test_input_1, tier.expected)
+	}
+
+	allowExact, allowAny, allowDefault = false, false, false
+	fsm.Reset()
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch err.(type) {
+	case GuardRejectedError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+// Test that spinning a clone leaves the original alone.
+func TestClone(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	clone := fsm.Clone()
+	assertState(t, ctx, clone, test_input_1, test_state_2)
+
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Spinning clone changed original. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	// A clone of a machine that has moved starts over at the initial state.
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if fsm.Clone().Current() != test_state_1 {
+		t.Errorf("Clone didn't start at initial state.")
+	}
+
+	// Final states, history and undo carry over.
+	fsm.SetFinalStates(test_state_2)
+	fsm.EnableHistory(4)
+	fsm.EnableUndo(2)
+	clone = fsm.Clone()
+	assertState(t, ctx, clone, test_input_1, test_state_2)
+	select {
+	case <-clone.Done():
+	default:
+		t.Errorf("Clone not done in final state.")
+	}
+	if len(clone.History()) != 1 {
+		t.Errorf("Wrong clone history length. (Expected: 1, Got: %v)", len(clone.History()))
+	}
+	if err := clone.Undo(); err != nil || clone.Current() != test_state_1 {
+		t.Errorf("Clone didn't undo. (Expected: %v, Got: %v, %v)", test_state_1, clone.Current(), err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if fsm.InitialState() != state1.Index {
+		t.Errorf("Wrong initial state. (Expected: %v, Got: %v)", state1.Index, fsm.InitialState())
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+
+	fsm.Reset()
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Reset didn't return to initial state. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+}
+
+// Test that we error if an outcome points to a state that was never defined.
+func TestUndefinedTarget(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+
+	_, err := Define(state1, state2)
+	if err == nil {
+		t.Fatalf("Didn't error creating FSM with undefined target state.")
+	}
+	switch e := err.(type) {
+	case UndefinedTargetError:
+		if e.FromState != test_state_2 || e.Input != test_input_2 || e.Target != test_state_3 {
+			t.Errorf("Wrong error details: %+v", e)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+// Test that ANY_INPUT catches unlisted inputs, but exact matches win.
+func TestAnyInput(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			ANY_INPUT:    Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	t.Log("1: 1 -> 2")
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	t.Log("3: 2 -> 1")
+	assertState(t, ctx, fsm, test_input_3, test_state_1)
+
+	t.Log("2: 1 -> 3")
+	assertState(t, ctx, fsm, test_input_2, test_state_3)
+}
+
+// Test that the default outcome only fires after the per-state lookup misses.
+func TestDefaultOutcome(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	_, err = fsm.Spin(ctx, test_input_2)
+	switch err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	// A default outcome leading out of the definition is refused.
+	err = fsm.SetDefaultOutcome(Outcome{State: 42, Action: NO_ACTION})
+	switch e := err.(type) {
+	case UndefinedTargetError:
+		if e.Target != 42 {
+			t.Errorf("Wrong target. (Expected: %v, Got: %v)", 42, e.Target)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if _, err = fsm.Spin(ctx, test_input_2); err == nil {
+		t.Fatal("Refused default outcome was used")
+	}
+
+	if err := fsm.SetDefaultOutcome(Outcome{State: test_state_3, Action: NO_ACTION}); err != nil {
+		t.Fatal("Failed to set default outcome: ", err)
+	}
+
+	t.Log("1: 1 -> 2 (exact)")
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	t.Log("2: 2 -> 1 (wildcard)")
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+
+	t.Log("2: 1 -> 3 (default)")
+	assertState(t, ctx, fsm, test_input_2, test_state_3)
+}
+
+func TestSpinAll(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	_, n, err := fsm.SpinAll(ctx, test_input_1, test_input_2, test_input_3, test_input_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if n != 4 || fsm.Current() != test_state_2 {
+		t.Errorf("Wrong batch result. (Expected: 4 inputs in state %v, Got: %v inputs in state %v)", test_state_2, n, fsm.Current())
+	}
+
+	// The batch stops at the first invalid input.
+	_, n, err = fsm.SpinAll(ctx, test_input_2, test_input_2, test_input_3)
+	switch err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if n != 1 || fsm.Current() != test_state_3 {
+		t.Errorf("Wrong batch result. (Expected: 1 input in state %v, Got: %v inputs in state %v)", test_state_3, n, fsm.Current())
+	}
+}
+
+func TestStep(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index:    test_state_2,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if err := fsm.Step(test_input_1); err != nil {
+		t.Fatal(err.Error())
+	}
+	if fsm.Current() != test_state_2 {
+		t.Errorf("Wrong state after step. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+
+	err = fsm.Step(test_input_1)
+	switch err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestLastChanged(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			// Loops through state 2 and back.
+			test_input_2: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: SAME_STATE},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if fsm.LastChanged() {
+		t.Error("LastChanged true before any spin")
+	}
+
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+	if fsm.LastChanged() {
+		t.Error("LastChanged true after a chain back to the same state")
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if !fsm.LastChanged() {
+		t.Error("LastChanged false after moving to another state")
+	}
+
+	if _, spun, err := fsm.SpinIfChanged(ctx, test_input_3); err != nil || spun {
+		t.Fatalf("Unchanging input not skipped. (Spun: %v, Error: %v)", spun, err)
+	}
+	if fsm.LastChanged() {
+		t.Error("LastChanged not reset by a skipped spin")
+	}
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	if _, err := fsm.Spin(ctx, test_input_1); err == nil {
+		t.Fatal("Expected an error for an invalid input")
+	}
+	if fsm.LastChanged() {
+		t.Error("LastChanged not reset by a rejected spin")
+	}
+}
+
+func TestSpinUntil(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index:    test_state_3,
+		Outcomes: map[Input]Outcome{},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var visited []int
+	_, err = fsm.SpinUntil(ctx, func(current int) (Input, bool) {
+		visited = append(visited, current)
+		return test_input_1, len(visited) < 4
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(visited) != 4 || visited[3] != test_state_2 || fsm.Current() != test_state_2 {
+		t.Errorf("Wrong states visited. (Expected: %v, Got: %v)", []int{test_state_1, test_state_2, test_state_1, test_state_2}, visited)
+	}
+
+	// Spin errors stop the generator right away.
+	calls := 0
+	_, err = fsm.SpinUntil(ctx, func(current int) (Input, bool) {
+		calls++
+		return test_input_2, true
+	})
+	switch err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if calls != 2 || fsm.Current() != test_state_3 {
+		t.Errorf("Generator not stopped at error. (Expected: %v calls, Got: %v)", 2, calls)
+	}
+}
+
+func TestOutcomesFor(t *testing.T) {
+	ctx := context.Background()
+
+	outcomes := OutcomesFor([]Input{test_input_1, test_input_2}, Outcome{State: test_state_2, Action: NO_ACTION})
+	outcomes[test_input_3] = Outcome{State: test_state_1, Action: NO_ACTION}
+	state1 := State{
+		Index:    test_state_1,
+		Outcomes: outcomes,
+	}
+	state2 := State{
+		Index:    test_state_2,
+		Outcomes: OutcomesFor([]Input{test_input_1, test_input_3}, Outcome{State: test_state_1, Action: NO_ACTION}),
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	assertState(t, ctx, fsm, test_input_3, test_state_1)
+	assertState(t, ctx, fsm, test_input_2, test_state_2)
+	assertState(t, ctx, fsm, test_input_3, test_state_1)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+}
+
+func TestPause(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	fsm.Pause()
+	if !fsm.IsPaused() {
+		t.Errorf("FSM not paused after Pause.")
+	}
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch err.(type) {
+	case PausedError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Paused FSM moved. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	fsm.Resume()
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+}
+
+func TestSpinIfChanged(t *testing.T) {
+	ctx := context.Background()
+
+	hits := 0
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_1, Action: func(ctx context.Context) (context.Context, Input) {
+				hits++
+				return ctx, NO_INPUT
+			}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	steps := []struct {
+		in      Input
+		spun    bool
+		current int
+	}{
+		{test_input_2, false, test_state_1},
+		{test_input_3, true, test_state_1},
+		{test_input_1, true, test_state_2},
+	}
+	for _, step := range steps {
+		_, spun, err := fsm.SpinIfChanged(ctx, step.in)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if spun != step.spun || fsm.Current() != step.current {
+			t.Errorf("Wrong result for input %v. (Expected: %v in state %v, Got: %v in state %v)", step.in, step.spun, step.current, spun, fsm.Current())
+		}
+	}
+	if hits != 1 {
+		t.Errorf("Self-transition with an action was skipped.")
+	}
+
+	if _, _, err := fsm.SpinIfChanged(ctx, test_input_1); err == nil {
+		t.Errorf("Didn't error on invalid input.")
+	}
+}
+
+func TestSpinOverride(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	// Inject a failing action.
+	failure := errors.New("injected")
+	_, err = fsm.SpinOverride(ctx, test_input_1, Outcome{State: test_state_2,
+		ActionE: func(ctx context.Context) (context.Context, Input, error) { return ctx, NO_INPUT, failure }})
+	if e, ok := err.(ActionError); !ok || e.Err != failure || fsm.Current() != test_state_1 {
+		t.Errorf("Override action didn't fail the spin. (Got: %v in state %v)", err, fsm.Current())
+	}
+
+	// Redirect a transition, then carry on with the chained input as defined.
+	_, err = fsm.SpinOverride(ctx, test_input_1, Outcome{State: test_state_3,
+		Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_1 }})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Wrong state after override. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	// The definition is untouched.
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	_, err = fsm.SpinOverride(ctx, test_input_1, Outcome{State: 7})
+	switch err.(type) {
+	case UndefinedTargetError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	ctx := context.Background()
+
+	allow := false
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION, Guard: func(ctx context.Context) bool { return allow }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if !fsm.Accepts(test_input_1) || !fsm.Accepts(test_input_2) || fsm.Accepts(test_input_3) || fsm.Accepts(NO_INPUT) {
+		t.Errorf("Wrong inputs accepted in state %v.", fsm.Current())
+	}
+	if fsm.AcceptsWithGuards(ctx, test_input_2) {
+		t.Errorf("Input accepted despite guard.")
+	}
+	allow = true
+	if !fsm.AcceptsWithGuards(ctx, test_input_2) {
+		t.Errorf("Input not accepted with passing guard.")
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if !fsm.Accepts(test_input_3) {
+		t.Errorf("Wildcard input not accepted.")
+	}
+}
+
+// Test that readers and spinners can share a machine. Run with -race.
+func TestConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := fsm.Spin(ctx, test_input_1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if s := fsm.Current(); s != test_state_1 && s != test_state_2 {
+					t.Errorf("FSM in unexpected state: %v", s)
+					return
+				}
+				fsm.IsTerminal()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Test that a failing action stops the chain in the state it ran from.
+func TestActionError(t *testing.T) {
+	ctx := context.Background()
+
+	failure := errors.New("boom")
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+			test_input_3: Outcome{State: test_state_3},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3,
+				ActionE: func(ctx context.Context) (context.Context, Input, error) { return ctx, NO_INPUT, failure }},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch e := err.(type) {
+	case ActionError:
+		if e.Err != failure || e.StateIndex != test_state_2 || e.Input != test_input_2 {
+			t.Errorf("Wrong error details: %+v", e)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_2 {
+		t.Errorf("FSM in wrong state after failed action. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+
+	// An Outcome without any action behaves like NO_ACTION.
+	fsm.Reset()
+	assertState(t, ctx, fsm, test_input_3, test_state_3)
+}
+
+func TestOutcome(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION, Meta: map[string]string{"label": "go"}},
+			ANY_INPUT:    Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetDefaultOutcome(Outcome{State: test_state_2, Action: NO_ACTION})
+
+	do, ok := fsm.Outcome(test_state_1, test_input_1)
+	if !ok || do.State != test_state_2 || do.Meta["label"] != "go" {
+		t.Errorf("Wrong outcome. (Expected: %v, Got: %v, %v)", test_state_2, do.State, ok)
+	}
+
+	// Wildcards and the default outcome don't count.
+	if do, ok := fsm.Outcome(test_state_1, test_input_2); ok {
+		t.Errorf("Unexpected outcome for an input only matched by ANY_INPUT: %v", do.State)
+	}
+	if do, ok := fsm.Outcome(test_state_1, ANY_INPUT); !ok || do.State != test_state_1 {
+		t.Errorf("Wrong ANY_INPUT outcome. (Expected: %v, Got: %v, %v)", test_state_1, do.State, ok)
+	}
+	if _, ok := fsm.Outcome(test_state_2, test_input_1); ok {
+		t.Error("Unexpected outcome for a state without outcomes")
+	}
+	if _, ok := fsm.Outcome(7, test_input_1); ok {
+		t.Error("Unexpected outcome for an undefined state")
+	}
+}
+
+func TestSameState(t *testing.T) {
+	ctx := context.Background()
+
+	runs := 0
+	count := func(ctx context.Context) (context.Context, Input) {
+		runs++
+		return ctx, NO_INPUT
+	}
+
+	stay := map[Input]Outcome{
+		test_input_1: Stay(count),
+		test_input_2: Outcome{State: SAME_STATE, Action: NO_ACTION, Internal: true},
+	}
+	state1 := State{
+		Index:    test_state_1,
+		Outcomes: OutcomesFor([]Input{test_input_3}, Outcome{State: test_state_2, Action: NO_ACTION}),
+	}
+	for in, do := range stay {
+		state1.Outcomes[in] = do
+	}
+	state2 := State{
+		Index:    test_state_2,
+		Outcomes: stay,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	assertState(t, ctx, fsm, test_input_3, test_state_2)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_2, test_state_2)
+	if runs != 2 {
+		t.Errorf("Wrong number of action runs. (Expected: 2, Got: %v)", runs)
+	}
+
+	if next, err := fsm.Peek(test_input_1); err != nil || next != test_state_2 {
+		t.Errorf("Wrong peeked state. (Expected: %v, Got: %v, %v)", test_state_2, next, err)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	hit := false
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { hit = true; return ctx, NO_INPUT }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	next, err := fsm.Peek(test_input_1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if next != test_state_2 {
+		t.Errorf("Wrong peeked state. (Expected: %v, Got: %v)", test_state_2, next)
+	}
+	if hit || fsm.Current() != test_state_1 {
+		t.Errorf("Peek had side effects. (Action hit: %v, State: %v)", hit, fsm.Current())
+	}
+
+	_, err = fsm.Peek(test_input_2)
+	switch err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestActionTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	type key struct{}
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					<-ctx.Done()
+					return ctx, NO_INPUT
+				}},
+			test_input_2: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) {
+					return context.WithValue(ctx, key{}, "value"), test_input_1
+				}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetActionTimeout(10 * time.Millisecond)
+
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch err.(type) {
+	case ActionTimeoutError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("FSM moved after action timed out. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	// Fast actions chain normally, and their context values survive without the deadline.
+	ctx, err = fsm.Spin(ctx, test_input_2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if ctx.Value(key{}) != "value" {
+		t.Errorf("Context value lost after timed action.")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("Action deadline leaked into returned context.")
+	}
+}
+
+func TestDefineWithStart(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := DefineWithStart(test_state_2, state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if fsm.Current() != test_state_2 {
+		t.Errorf("FSM didn't start in start state. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	_, err = DefineWithStart(test_state_3, state1, state2)
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestDefineSlice(t *testing.T) {
+	ctx := context.Background()
+
+	states := []State{
+		State{
+			Index: test_state_1,
+			Outcomes: map[Input]Outcome{
+				test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			},
+		},
+		State{
+			Index: test_state_2,
+		},
+	}
+
+	fsm, err := DefineSlice(states)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	for _, states := range [][]State{nil, append(states, states[0])} {
+		_, err := DefineSlice(states)
+		_, expected := Define(states...)
+		if err != expected {
+			t.Errorf("Wrong error. (Expected: %v, Got: %v)", expected, err)
+		}
+		t.Logf("FSM corrently returned error: %v", err)
+	}
+}
+
+func TestMustDefine(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+	// Nothing leads to state 3.
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	if fsm := MustDefine(state1, state2); fsm.Current() != test_state_1 {
+		t.Errorf("Wrong initial state. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	mustPanic := func(want string, states ...State) {
+		defer func() {
+			r := recover()
+			msg, ok := r.(string)
+			if !ok || !strings.Contains(msg, want) {
+				t.Errorf("Wrong panic. (Expected: %q, Got: %v)", want, r)
+			}
+			t.Logf("FSM corrently panicked: %v", r)
+		}()
+		MustDefine(states...)
+	}
+	mustPanic(UnreachableStateError(test_state_3).Error(), state1, state2, state3)
+	mustPanic(ClashingStateError(test_state_1).Error(), state1, state1)
+
+	// State 3 is only reached through a Chooser, which needs the unreachable check downgraded.
+	choice := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: CHOICE, Chooser: func(ctx context.Context) int { return test_state_3 }},
+		},
+	}
+	mustPanic(UnreachableStateError(test_state_3).Error(), choice, state3)
+	fsm := MustDefineWithWarnings(CheckUnreachableStates, choice, state3)
+	assertState(t, context.Background(), fsm, test_input_1, test_state_3)
+}
+
+func TestValidInputs(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			ANY_INPUT:    Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetLogger(nil, nil, map[Input]string{test_input_1: "one"})
+
+	inputs := fsm.ValidInputs()
+	if len(inputs) != 2 || inputs[0] != test_input_1 || inputs[1] != test_input_3 {
+		t.Errorf("Wrong valid inputs. (Expected: %v, Got: %v)", []Input{test_input_1, test_input_3}, inputs)
+	}
+
+	names := fsm.ValidInputNames()
+	if len(names) != 2 || names[0] != "one" || names[1] != "" {
+		t.Errorf("Wrong valid input names. (Expected: %q, Got: %q)", []string{"one", ""}, names)
+	}
+}
+
+// Test that Spin logs carry structured fields.
+func TestLogFields(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+	logger.Level = logrus.TraceLevel
+	logger.Formatter = &logrus.JSONFormatter{}
+	fsm.SetLogger(logger, map[int]string{test_state_1: "one", test_state_2: "two"}, map[Input]string{test_input_1: "go"})
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	found := false
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse log line %q: %v", line, err)
+		}
+		if entry["next_state"] == nil {
+			continue
+		}
+		found = true
+		if entry["state_name"] != "one" || entry["input_name"] != "go" || entry["next_state"] != float64(test_state_2) {
+			t.Errorf("Wrong transition log fields: %v", entry)
+		}
+	}
+	if !found {
+		t.Errorf("No transition log line found in: %s", buf.String())
+	}
+}
+
+// Test that log entries aren't built while the logger is quiet.
+func TestLogQuiet(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	logger := logrus.New()
+	logger.Level = logrus.InfoLevel
+	fsm.SetLogger(logger, nil, nil)
+	named := 0
+	fsm.SetNamedTypes(func(int) string { named++; return "" }, func(Input) string { named++; return "" })
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := fsm.Spin(ctx, test_input_1); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Spin allocated with tracing off. (Expected: 0, Got: %v)", allocs)
+	}
+	if named != 0 {
+		t.Errorf("Names looked up with tracing off. (Expected: 0, Got: %v)", named)
+	}
+}
+
+type testStateEnum int
+
+func (s testStateEnum) String() string {
+	return [...]string{"one", "two"}[s]
+}
+
+// Test that names can be computed by functions instead of maps.
+func TestNamedTypes(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetLogger(nil, map[int]string{test_state_1: "map"}, map[Input]string{test_input_2: "back"})
+	fsm.SetNamedTypes(func(s int) string { return testStateEnum(s).String() }, nil)
+
+	if fsm.CurrentName() != "one" {
+		t.Errorf("Wrong state name. (Expected: %v, Got: %v)", "one", fsm.CurrentName())
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if fsm.CurrentName() != "two" {
+		t.Errorf("Wrong state name. (Expected: %v, Got: %v)", "two", fsm.CurrentName())
+	}
+	// Inputs still use the map.
+	if names := fsm.ValidInputNames(); len(names) != 1 || names[0] != "back" {
+		t.Errorf("Wrong input names. (Expected: %v, Got: %v)", []string{"back"}, names)
+	}
+
+	errs := fsm.ValidateNames()
+	if len(errs) != 1 || errs[0] != (NameError{"input", test_input_1, false}) {
+		t.Errorf("Wrong name errors. (Expected: %v, Got: %v)", []error{NameError{"input", test_input_1, false}}, errs)
+	}
+}
+
+// Test that a logger entry's fields are kept in Spin logs.
+func TestFieldLogger(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+	logger.Level = logrus.TraceLevel
+	fsm.SetLogger(logger.WithField("component", "fsm"), nil, nil)
+
+	// A nil *logrus.Logger keeps the entry.
+	var none *logrus.Logger
+	fsm.SetLogger(none, nil, nil)
+
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if !strings.Contains(buf.String(), "component=fsm") {
+		t.Errorf("Entry fields missing from log output: %s", buf.String())
+	}
+}
+
+func TestErrorLogLevel(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+	logger.Level = logrus.WarnLevel
+	fsm.SetLogger(logger, nil, nil)
+
+	fsm.Step(test_input_2)
+	if !strings.Contains(buf.String(), "level=warning") || !strings.Contains(buf.String(), "input invalid") {
+		t.Errorf("Invalid input not logged as a warning: %s", buf.String())
+	}
+
+	buf.Reset()
+	fsm.SetState(test_state_1)
+	fsm.current = 7
+	fsm.Step(test_input_1)
+	if !strings.Contains(buf.String(), "level=error") {
+		t.Errorf("Impossible state not logged as an error: %s", buf.String())
+	}
+
+	buf.Reset()
+	fsm.current = test_state_1
+	fsm.SetErrorLogLevel(logrus.TraceLevel)
+	fsm.Step(test_input_2)
+	if buf.Len() != 0 {
+		t.Errorf("Invalid input logged above trace level: %s", buf.String())
+	}
+
+	// The shared default logger stays silent.
+	quiet, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	quiet.Step(test_input_2)
+	if quietLogger.Level != logrus.FatalLevel {
+		t.Errorf("Default logger level changed to %v", quietLogger.Level)
+	}
+	if _, err := quiet.Spin(ctx, test_input_1); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+// Test that an invalid input returned by an action reports where the chain stalled.
+func TestChainStalled(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_3 }},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch e := err.(type) {
+	case InvalidInputError:
+		if e.Depth != 3 || e.Origin != test_input_1 || e.Input != test_input_3 || e.StateIndex != test_state_1 || e.Source != ACTION_INPUT {
+			t.Errorf("Wrong error details: %+v", e)
+		}
+		if !strings.Contains(e.Error(), "returned by action") {
+			t.Errorf("Error doesn't blame the action: %v", e.Error())
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestStart(t *testing.T) {
+	ctx := context.Background()
+
+	entered := 0
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+		OnEnter: func(ctx context.Context) context.Context { entered++; return ctx },
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if entered != 0 {
+		t.Fatalf("OnEnter ran before Start.")
+	}
+
+	if _, err := fsm.Start(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := fsm.Start(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+	if entered != 1 {
+		t.Errorf("Wrong number of OnEnter calls after Start. (Expected: %v, Got: %v)", 1, entered)
+	}
+}
+
+// Test that internal self-transitions skip hooks, but external ones don't.
+func TestInternalTransition(t *testing.T) {
+	ctx := context.Background()
+
+	hooks := 0
+	hook := func(ctx context.Context) context.Context { hooks++; return ctx }
+	hit := false
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Internal: true,
+				Action: func(ctx context.Context) (context.Context, Input) { hit = true; return ctx, NO_INPUT }},
+			test_input_2: Outcome{State: test_state_1},
+		},
+		OnEnter: hook,
+		OnExit:  hook,
+	}
+
+	fsm, err := Define(state1)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.EnableHistory(10)
+
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if !hit || hooks != 0 || len(fsm.History()) != 0 {
+		t.Errorf("Internal transition misbehaved. (Action hit: %v, Hooks: %v, History: %v)", hit, hooks, fsm.History())
+	}
+
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+	if hooks != 2 || len(fsm.History()) != 1 {
+		t.Errorf("External self-transition misbehaved. (Hooks: %v, History: %v)", hooks, fsm.History())
+	}
+}
+
+func TestChoice(t *testing.T) {
+	ctx := context.Background()
+
+	target := test_state_2
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: CHOICE, Chooser: func(ctx context.Context) int { return target }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	target = test_state_3
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	target = 7
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("FSM moved after invalid choice. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+}
+
+// Compare defining an FSM with the shared default logger to giving each FSM its own logger. Run with -benchmem.
+func BenchmarkDefine(b *testing.B) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	b.Run("default logger", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if _, err := Define(state1, state2); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("own logger", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			fsm, err := Define(state1, state2)
+			if err != nil {
+				b.Fatal(err)
+			}
+			log := logrus.New()
+			log.Level = logrus.FatalLevel
+			fsm.SetLogger(log, nil, nil)
+		}
+	})
+}
+
+// Spinning with logging off shouldn't pay for log entries. Run with -benchmem.
+func BenchmarkSpin(b *testing.B) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := fsm.Spin(ctx, test_input_1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
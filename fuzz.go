@@ -0,0 +1,63 @@
+package fsm
+
+import (
+	"context"
+	"math/rand"
+)
+
+// FuzzInputs drives the FSM with steps pseudo-random inputs, each picked among those the current state
+// has an Outcome for, and returns every hop taken. The same seed picks the same inputs for the same FSM.
+// States whose only Outcome is ANY_INPUT are given one of the inputs used anywhere in the definition.
+// The run stops early once the FSM reaches a state without any input to pick.
+//
+// Since only valid inputs are picked, an InvalidInputError can only come from an input chained by an action.
+// Any error other than a GuardRejectedError, which merely skips the step, stops the run and is returned
+// along with the hops taken so far: it points at a real bug in the definition or its actions.
+// Actions run as they would in a normal Spin.
+// The lock is held for the whole run.
+// This method is thread-safe.
+func (f *FSM) FuzzInputs(ctx context.Context, seed int64, steps int) ([]Transition, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	rnd := rand.New(rand.NewSource(seed))
+
+	used := map[Input]Outcome{}
+	for _, s := range f.states {
+		for in, do := range s.Outcomes {
+			if in >= 0 {
+				used[in] = do
+			}
+		}
+	}
+	all := sortedInputs(used)
+
+	path := []Transition{}
+	for n := 0; n < steps; n++ {
+		var inputs []Input
+		_, wildcard := f.states[f.current].Outcomes[ANY_INPUT]
+		for _, in := range f.validInputs() {
+			if in != CHILD_DONE && in != ACTION_FAILED {
+				inputs = append(inputs, in)
+			}
+		}
+		if len(inputs) == 0 && wildcard {
+			inputs = all
+		}
+		if len(inputs) == 0 {
+			break
+		}
+
+		var hops []Transition
+		var err error
+		ctx, hops, err = f.spin(ctx, inputs[rnd.Intn(len(inputs))], true)
+		path = append(path, hops...)
+		if _, ok := err.(GuardRejectedError); ok {
+			continue
+		}
+		if err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,73 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFuzzInputs(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	path, err := fsm.FuzzInputs(ctx, 1, 50)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(path) != 50 {
+		t.Errorf("Wrong number of hops. (Expected: %v, Got: %v)", 50, len(path))
+	}
+
+	// The same seed walks the same path.
+	fsm.Reset()
+	again, err := fsm.FuzzInputs(ctx, 1, 50)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i := range path {
+		if path[i].From != again[i].From || path[i].To != again[i].To || path[i].Input != again[i].Input {
+			t.Fatalf("Same seed walked a different path at hop %v.", i)
+		}
+	}
+
+	// A broken action is reported.
+	state3.Outcomes[test_input_3] = Outcome{State: test_state_1,
+		Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_3 }}
+	broken, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	_, err = broken.FuzzInputs(ctx, 1, 50)
+	switch e := err.(type) {
+	case InvalidInputError:
+		if e.Source != ACTION_INPUT {
+			t.Errorf("Wrong input source. (Expected: %v, Got: %v)", ACTION_INPUT, e.Source)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
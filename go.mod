@@ -1,5 +1,14 @@
 module github.com/maxim0r/fsm
 
-go 1.12
+go 1.18
 
-require github.com/sirupsen/logrus v1.4.0
+require (
+	github.com/sirupsen/logrus v1.4.0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	golang.org/x/crypto v0.0.0-20180904163835-0709b304e793 // indirect
+	golang.org/x/sys v0.0.0-20180905080454-ebe1bf3edb33 // indirect
+)
@@ -0,0 +1,65 @@
+package fsm
+
+// A Graph is a plain data copy of an FSM definition, detached from the live FSM, for editors and exporters.
+type Graph struct {
+	Nodes []NodeInfo
+	Edges []EdgeInfo
+}
+
+// NodeInfo describes a state of a Graph.
+// Name is the name set by SetLogger or SetNamedTypes, or the empty string if there is none.
+// Terminal states have no Outcomes, and Reachable tells whether the state can be reached from the initial state,
+// as computed by UnreachableStates.
+type NodeInfo struct {
+	Index     int
+	Name      string
+	Terminal  bool
+	Initial   bool
+	Current   bool
+	Reachable bool
+}
+
+// EdgeInfo describes a transition of a Graph.
+// To is CHOICE when the target is picked at spin time, and SAME_STATE is resolved to From.
+// HasAction is false for Outcomes which only change state, including those running NO_ACTION.
+type EdgeInfo struct {
+	From      int
+	To        int
+	Input     Input
+	InputName string
+	HasAction bool
+}
+
+// Graph returns the states and transitions of the FSM as plain data.
+// Nodes are sorted by index, and edges by state and then by input, so the result is deterministic.
+// This method is thread-safe.
+func (f *FSM) Graph() Graph {
+	f.RLock()
+	defer f.RUnlock()
+
+	reached := f.reachableFrom(f.initial)
+
+	g := Graph{Nodes: []NodeInfo{}, Edges: []EdgeInfo{}}
+	for _, index := range f.sortedStates() {
+		s := f.states[index]
+		g.Nodes = append(g.Nodes, NodeInfo{
+			Index:     index,
+			Name:      f.getStateName(index),
+			Terminal:  len(s.Outcomes) == 0,
+			Initial:   index == f.initial,
+			Current:   index == f.current,
+			Reachable: reached[index],
+		})
+		for _, in := range sortedInputs(s.Outcomes) {
+			do := s.Outcomes[in]
+			g.Edges = append(g.Edges, EdgeInfo{
+				From:      index,
+				To:        do.target(index),
+				Input:     in,
+				InputName: f.getInputName(in),
+				HasAction: do.hasAction(),
+			})
+		}
+	}
+	return g
+}
@@ -0,0 +1,69 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: func(ctx context.Context) (context.Context, Input) { return ctx, NO_INPUT }},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetLogger(nil, map[int]string{test_state_1: "idle"}, map[Input]string{test_input_1: "go"})
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	g := fsm.Graph()
+
+	nodes := []NodeInfo{
+		{Index: test_state_1, Name: "idle", Initial: true, Reachable: true},
+		{Index: test_state_2, Terminal: true, Current: true, Reachable: true},
+		{Index: test_state_3},
+	}
+	if len(g.Nodes) != len(nodes) {
+		t.Fatalf("Wrong nodes. (Expected: %v, Got: %v)", nodes, g.Nodes)
+	}
+	for i := range nodes {
+		if g.Nodes[i] != nodes[i] {
+			t.Errorf("Wrong node %d. (Expected: %+v, Got: %+v)", i, nodes[i], g.Nodes[i])
+		}
+	}
+
+	edges := []EdgeInfo{
+		{From: test_state_1, To: test_state_2, Input: test_input_1, InputName: "go"},
+		{From: test_state_1, To: test_state_1, Input: test_input_2, HasAction: true},
+		{From: test_state_3, To: test_state_1, Input: test_input_1, InputName: "go"},
+	}
+	if len(g.Edges) != len(edges) {
+		t.Fatalf("Wrong edges. (Expected: %v, Got: %v)", edges, g.Edges)
+	}
+	for i := range edges {
+		if g.Edges[i] != edges[i] {
+			t.Errorf("Wrong edge %d. (Expected: %+v, Got: %+v)", i, edges[i], g.Edges[i])
+		}
+	}
+
+	if _, err := json.Marshal(g); err != nil {
+		t.Errorf("Graph can't be serialized: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package fsm
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// delegate spins an input on the Child machine of a composite state.
+// It returns the next input for the parent to chain, and whether the child handled the input.
+// The caller must hold the lock.
+func (f *FSM) delegate(ctx context.Context, s State, in Input) (context.Context, Input, bool, error) {
+	if s.Child.IsTerminal() {
+		return ctx, in, false, nil
+	}
+
+	s.Child.Lock()
+	ctx, _, err := s.Child.spinChain(ctx, in, false)
+	s.Child.Unlock()
+	if e, ok := err.(InvalidInputError); ok && e.Source == EXTERNAL_INPUT {
+		if f.logs(logrus.TraceLevel) {
+			f.logEntry(f.current, in).Tracef("FSM: input [%d][%s] not handled by child of state [%d][%s]", in, f.getInputName(in), f.current, f.getStateName(f.current))
+		}
+		return ctx, in, false, nil
+	} else if err != nil {
+		return ctx, in, false, err
+	}
+
+	if f.logs(logrus.TraceLevel) {
+		f.logEntry(f.current, in).Tracef("FSM: input [%d][%s] handled by child of state [%d][%s]", in, f.getInputName(in), f.current, f.getStateName(f.current))
+	}
+	if _, ok := s.Outcomes[CHILD_DONE]; ok && s.Child.IsTerminal() {
+		return ctx, CHILD_DONE, true, nil
+	}
+	return ctx, NO_INPUT, true, nil
+}
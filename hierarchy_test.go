@@ -0,0 +1,67 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChild(t *testing.T) {
+	ctx := context.Background()
+
+	// The child runs 1 -> 2 -> 3, where 3 is terminal.
+	child, err := NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_2).
+		AddState(test_state_2).
+		On(test_input_1).Go(test_state_3).
+		AddState(test_state_3).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build child FSM: ", err)
+	}
+
+	// The parent leaves the composite state on input 2 or when the child is done.
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_2},
+			CHILD_DONE:   Outcome{State: test_state_3},
+		},
+		Child: child,
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	t.Log("1: child 1 -> 2")
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if child.Current() != test_state_2 {
+		t.Errorf("Child in wrong state. (Expected: %v, Got: %v)", test_state_2, child.Current())
+	}
+
+	t.Log("2: falls through, 1 -> 2")
+	assertState(t, ctx, fsm, test_input_2, test_state_2)
+
+	t.Log("2: 2 -> 1, child reset")
+	assertState(t, ctx, fsm, test_input_2, test_state_1)
+	if child.Current() != test_state_1 {
+		t.Errorf("Child not reset on entry. (Expected: %v, Got: %v)", test_state_1, child.Current())
+	}
+
+	t.Log("1: child 1 -> 2")
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	t.Log("1: child 2 -> 3, done, 1 -> 3")
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+}
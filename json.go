@@ -0,0 +1,36 @@
+package fsm
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// DefineFromJSON defines an FSM from a JSON document of the form:
+//
+//	{
+//		"states": [
+//			{"index": 0, "outcomes": [{"input": 1, "state": 1}]},
+//			{"index": 1, "outcomes": [{"input": 1, "state": 0}]}
+//		]
+//	}
+//
+// The first state is the initial state.
+// Outcomes naming an action run the one registered with RegisterAction under that name,
+// and the others run NO_ACTION; use DefineFromJSONWithActions to provide actions directly.
+// An outcome may also carry an object of string labels under "meta", which becomes the Meta of its Outcome,
+// and the document may name states and inputs with objects under "state_names" and "input_names".
+func DefineFromJSON(r io.Reader) (*FSM, error) {
+	return DefineFromJSONWithActions(r, nil)
+}
+
+// DefineFromJSONWithActions defines an FSM from a JSON document like DefineFromJSON,
+// resolving the optional "action" name of each outcome against actions before the registered ones.
+// Will return an UnknownActionError if an outcome names an action which is neither in the map nor registered,
+// as well as any error Define would return.
+func DefineFromJSONWithActions(r io.Reader, actions map[string]Action) (*FSM, error) {
+	var d definition
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	return d.define(actions)
+}
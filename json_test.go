@@ -0,0 +1,103 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testJSON = `{
+	"states": [
+		{"index": 0, "outcomes": [{"input": 0, "state": 1, "action": "hit"}, {"input": 1, "state": 0}]},
+		{"index": 1, "outcomes": [{"input": 0, "state": 0}]}
+	]
+}`
+
+func TestDefineFromJSON(t *testing.T) {
+	ctx := context.Background()
+
+	hit := false
+	actions := map[string]Action{
+		"hit": func(ctx context.Context) (context.Context, Input) { hit = true; return ctx, NO_INPUT },
+	}
+
+	fsm, err := DefineFromJSONWithActions(strings.NewReader(testJSON), actions)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if !hit {
+		t.Errorf("Didn't hit named action.")
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+}
+
+func TestDefineFromJSONErrors(t *testing.T) {
+	_, err := DefineFromJSON(strings.NewReader(testJSON))
+	switch err.(type) {
+	case UnknownActionError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	_, err = DefineFromJSON(strings.NewReader(`{"states": [{"index": 0, "outcomes": [{"input": 0, "state": 5}]}]}`))
+	switch err.(type) {
+	case UndefinedTargetError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	_, err = DefineFromJSON(strings.NewReader(`{"states": [`))
+	if err == nil {
+		t.Fatalf("Didn't error on malformed JSON.")
+	}
+}
+
+func TestJSONNames(t *testing.T) {
+	doc := `{
+		"states": [{"index": 0, "outcomes": [{"input": 0, "state": 1}]}, {"index": 1, "outcomes": []}],
+		"state_names": {"0": "idle", "1": "busy"},
+		"input_names": {"0": "start"}
+	}`
+
+	fsm, err := DefineFromJSON(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	states, inputs := fsm.Names()
+	if len(states) != 2 || states[test_state_1] != "idle" || states[test_state_2] != "busy" {
+		t.Errorf("Wrong state names: %v", states)
+	}
+	if len(inputs) != 1 || inputs[test_input_1] != "start" {
+		t.Errorf("Wrong input names: %v", inputs)
+	}
+
+	// The exported maps can be stored back into a definition.
+	names, err := json.Marshal(map[string]interface{}{"state_names": states, "input_names": inputs})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	doc = `{"states": [{"index": 0, "outcomes": [{"input": 0, "state": 1}]}, {"index": 1, "outcomes": []}], ` + string(names[1:])
+	again, err := DefineFromJSON(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if s, i := again.Names(); fmt.Sprint(s, i) != fmt.Sprint(states, inputs) {
+		t.Errorf("Names lost in round trip. (Expected: %v %v, Got: %v %v)", states, inputs, s, i)
+	}
+
+	// Names are copies.
+	states[test_state_1] = "changed"
+	if fsm.CurrentName() != "idle" {
+		t.Errorf("Names returned the live map")
+	}
+
+	if s, i := MustDefine(State{Index: test_state_1}).Names(); s != nil || i != nil {
+		t.Errorf("Unset names not nil: %v %v", s, i)
+	}
+}
@@ -0,0 +1,71 @@
+package fsm
+
+// Merge combines the definitions of two FSMs into a new one, starting in the initial state of a.
+// States only defined in one of them are copied as is. A state defined in both must either have the same
+// Outcomes, compared like DefinitionEquals does, in which case the state of a is kept,
+// or be a placeholder in one of them: a state without Outcomes, hooks, Child or Timeout,
+// which a fragment can declare to point to a state defined by another fragment.
+// The placeholder gives way to the full state.
+// Will return a ClashingStateError for any other state defined in both,
+// as well as any error Define would return for the merged states.
+// Child machines are cloned, so the merged FSM doesn't share them with a or b.
+// The merged FSM has the settings of a freshly defined FSM: current states, names and settings aren't carried over.
+// This method is thread-safe.
+func Merge(a, b *FSM) (*FSM, error) {
+	a.RLock()
+	start := a.initial
+	a.RUnlock()
+
+	return MergeWithStart(start, a, b)
+}
+
+// MergeWithStart merges two FSMs like Merge, but starts in the given state instead of the initial state of a.
+// Will return an ImpossibleStateError if start isn't part of the merged definition,
+// as well as any error Merge would return.
+// This method is thread-safe.
+func MergeWithStart(start int, a, b *FSM) (*FSM, error) {
+	sa, _ := a.definition()
+	sb, _ := b.definition()
+
+	states := make([]State, 0, len(sa)+len(sb))
+	for _, index := range unionStates(sa, sb) {
+		s, inA := sa[index]
+		other, inB := sb[index]
+		switch {
+		case !inA:
+			s = other
+		case !inB:
+		case other.isPlaceholder():
+		case s.isPlaceholder():
+			s = other
+		case !sameOutcomes(s.Outcomes, other.Outcomes):
+			return nil, ClashingStateError(index)
+		}
+		if s.Child != nil {
+			s.Child = s.Child.Clone()
+		}
+		states = append(states, s)
+	}
+
+	return DefineWithStart(start, states...)
+}
+
+// isPlaceholder reports whether a state only stands in for one defined elsewhere.
+func (s State) isPlaceholder() bool {
+	return len(s.Outcomes) == 0 && s.OnEnter == nil && s.OnExit == nil && s.Child == nil && s.Timeout == nil
+}
+
+// sameOutcomes reports whether two Outcome maps route the same inputs to the same states, with the same weights,
+// and with an action on the same transitions.
+func sameOutcomes(a, b map[Input]Outcome) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for in, da := range a {
+		db, ok := b[in]
+		if !ok || da.State != db.State || !sameWeights(da.Weights, db.Weights) || da.hasAction() != db.hasAction() {
+			return false
+		}
+	}
+	return true
+}
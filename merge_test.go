@@ -0,0 +1,148 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+
+	// The first fragment hands over to state 3, which only the second one defines.
+	a, err := Define(
+		State{
+			Index: test_state_1,
+			Outcomes: map[Input]Outcome{
+				test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			},
+		},
+		State{
+			Index: test_state_2,
+			Outcomes: map[Input]Outcome{
+				test_input_2: Outcome{State: 3, Action: NO_ACTION},
+			},
+		},
+		State{Index: 3},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	b, err := Define(
+		State{
+			Index: 3,
+			Outcomes: map[Input]Outcome{
+				test_input_1: Outcome{State: 4, Action: NO_ACTION},
+			},
+		},
+		State{
+			Index: 4,
+			Outcomes: map[Input]Outcome{
+				test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
+			},
+		},
+		// Same Outcomes as in the first fragment.
+		State{
+			Index: test_state_2,
+			Outcomes: map[Input]Outcome{
+				test_input_2: Outcome{State: 3, Action: NO_ACTION},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	fsm, err := Merge(a, b)
+	if err != nil {
+		t.Fatal("Failed to merge FSMs: ", err)
+	}
+	if fsm.StateCount() != 4 {
+		t.Errorf("Wrong number of states. (Expected: 4, Got: %v)", fsm.StateCount())
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	assertState(t, ctx, fsm, test_input_2, 3)
+	assertState(t, ctx, fsm, test_input_1, 4)
+	assertState(t, ctx, fsm, test_input_2, test_state_2)
+
+	fsm, err = MergeWithStart(4, a, b)
+	if err != nil {
+		t.Fatal("Failed to merge FSMs: ", err)
+	}
+	if fsm.InitialState() != 4 {
+		t.Errorf("Wrong initial state. (Expected: 4, Got: %v)", fsm.InitialState())
+	}
+
+	// State 2 goes somewhere else in c.
+	c, err := Define(State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	_, err = Merge(a, c)
+	switch e := err.(type) {
+	case ClashingStateError:
+		if int(e) != test_state_2 {
+			t.Errorf("Wrong clashing state. (Expected: %v, Got: %v)", test_state_2, int(e))
+		}
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Errorf("Wrong error type: %T (%v)", err, err)
+	}
+
+	// Weighted Outcomes only match with the same weights.
+	weighted := func(weight float64) *FSM {
+		fsm, err := Define(
+			State{Index: test_state_2, Outcomes: map[Input]Outcome{test_input_1: WeightedOutcome(
+				WeightedTarget{State: test_state_2, Weight: 1}, WeightedTarget{State: 3, Weight: weight})}},
+			State{Index: 3},
+		)
+		if err != nil {
+			t.Fatal("Failed to define FSM: ", err)
+		}
+		return fsm
+	}
+	if _, err := Merge(weighted(1), weighted(1)); err != nil {
+		t.Errorf("Failed to merge identical weighted states: %v", err)
+	}
+	_, err = Merge(weighted(1), weighted(2))
+	switch err.(type) {
+	case ClashingStateError:
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Errorf("Wrong error type: %T (%v)", err, err)
+	}
+}
+
+func TestMergeChild(t *testing.T) {
+	ctx := context.Background()
+
+	child, err := NewBuilder().
+		AddState(test_state_1).
+		On(test_input_1).Go(test_state_2).
+		AddState(test_state_2).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build child FSM: ", err)
+	}
+	a, err := Define(State{Index: test_state_1, Child: child})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	b, err := Define(State{Index: test_state_2})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	fsm, err := Merge(a, b)
+	if err != nil {
+		t.Fatal("Failed to merge FSMs: ", err)
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if child.Current() != test_state_1 {
+		t.Errorf("Spinning the merged FSM moved the child of a. (Expected: %v, Got: %v)", test_state_1, child.Current())
+	}
+}
@@ -0,0 +1,84 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mermaidEscaper replaces the characters Mermaid treats specially in labels with entity codes.
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	":", "#58;",
+	";", "#59;",
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+)
+
+// ToMermaid renders the FSM definition as a Mermaid stateDiagram-v2.
+// States and transitions are labelled with the names set by SetLogger when present, and by their indices otherwise.
+// The initial state is marked with a start arrow, and terminal states with an end arrow.
+// CHOICE Outcomes go through a choice node, with an arrow labelled with its weight to every target of a weighted Outcome.
+// This method is thread-safe.
+func (f *FSM) ToMermaid() string {
+	f.RLock()
+	defer f.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+
+	indices := f.sortedStates()
+	for _, index := range indices {
+		fmt.Fprintf(&b, "    state \"%s\" as %s\n", mermaidEscaper.Replace(f.stateLabel(index)), mermaidID(index))
+	}
+
+	fmt.Fprintf(&b, "    [*] --> %s\n", mermaidID(f.initial))
+	for _, index := range indices {
+		s := f.states[index]
+		for _, in := range sortedInputs(s.Outcomes) {
+			target := mermaidID(s.Outcomes[in].target(index))
+			if s.Outcomes[in].State == CHOICE {
+				target = fmt.Sprintf("%s_choice_%s", mermaidID(index), strings.TrimPrefix(mermaidID(int(in)), "s"))
+				fmt.Fprintf(&b, "    state %s <<choice>>\n", target)
+				if s.Outcomes[in].Chooser == nil {
+					for _, t := range s.Outcomes[in].Weights {
+						fmt.Fprintf(&b, "    %s --> %s : %g\n", target, mermaidID(t.State), t.Weight)
+					}
+				}
+			}
+			fmt.Fprintf(&b, "    %s --> %s : %s\n", mermaidID(index), target, mermaidEscaper.Replace(f.inputLabel(in)))
+		}
+		if len(s.Outcomes) == 0 {
+			fmt.Fprintf(&b, "    %s --> [*]\n", mermaidID(index))
+		}
+	}
+
+	return b.String()
+}
+
+// mermaidID returns a Mermaid identifier for a state index.
+func mermaidID(index int) string {
+	if index < 0 {
+		return fmt.Sprintf("s_%d", -index)
+	}
+	return fmt.Sprintf("s%d", index)
+}
+
+// stateLabel returns the name of a state, or its index if it has no name.
+func (f *FSM) stateLabel(index int) string {
+	if name := f.getStateName(index); name != "" {
+		return name
+	}
+	return fmt.Sprint(index)
+}
+
+// inputLabel returns the name of an input, or its value if it has no name.
+func (f *FSM) inputLabel(in Input) string {
+	if name := f.getInputName(in); name != "" {
+		return name
+	}
+	if in == ANY_INPUT {
+		return "*"
+	}
+	return fmt.Sprint(int(in))
+}
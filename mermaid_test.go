@@ -0,0 +1,44 @@
+package fsm
+
+import (
+	"testing"
+)
+
+func TestToMermaid(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetLogger(nil, map[int]string{test_state_1: "idle", test_state_2: "busy"}, map[Input]string{test_input_1: "go: now"})
+
+	expected := `stateDiagram-v2
+    state "idle" as s0
+    state "busy" as s1
+    state "2" as s2
+    [*] --> s0
+    s0 --> s1 : go#58; now
+    s0 --> s2 : 1
+    s1 --> s0 : *
+    s2 --> [*]
+`
+	if got := fsm.ToMermaid(); got != expected {
+		t.Errorf("Wrong Mermaid output.\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,51 @@
+package fsm
+
+import (
+	"time"
+)
+
+// Metrics receives measurements from an FSM, so they can be exported to a monitoring system
+// without the FSM depending on one.
+// Methods are called while Spin holds the FSM lock, so they should be quick and must not use the FSM.
+type Metrics interface {
+	// TransitionObserved is called after every hop the FSM makes.
+	TransitionObserved(from, to int, in Input)
+	// ActionDuration is called after every action with how long it ran, whether or not it succeeded.
+	ActionDuration(state int, in Input, d time.Duration)
+}
+
+// SetMetrics sets where the FSM reports its measurements.
+// A nil Metrics stops reporting.
+// This method is thread-safe.
+func (f *FSM) SetMetrics(m Metrics) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.metrics = m
+}
+
+// SetSpinObserver sets a callback run once per input passed to Spin, after its whole action chain completes or fails.
+// It receives the state the FSM was in, the input, the number of hops taken and the total time spent,
+// which is what the caller of Spin waited for, unlike the per action ActionDuration.
+// The callback is run while Spin holds the FSM lock, so it should be quick and must not use the FSM.
+// A nil callback stops observing.
+// This method is thread-safe.
+func (f *FSM) SetSpinObserver(fn func(start int, in Input, hops int, d time.Duration)) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.spinObserver = fn
+}
+
+// SetRejectHandler sets a callback run whenever Spin is about to return an InvalidInputError,
+// with the state the FSM is in and the rejected input, which may have been chained by an action.
+// It can't change the outcome of Spin.
+// The callback is run while Spin holds the FSM lock, so it should be quick and must not use the FSM.
+// A nil callback removes it.
+// This method is thread-safe.
+func (f *FSM) SetRejectHandler(fn func(state int, in Input)) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.onReject = fn
+}
@@ -0,0 +1,138 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	transitions [][3]int
+	actions     int
+}
+
+func (m *testMetrics) TransitionObserved(from, to int, in Input) {
+	m.transitions = append(m.transitions, [3]int{from, to, int(in)})
+}
+
+func (m *testMetrics) ActionDuration(state int, in Input, d time.Duration) {
+	m.actions++
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	m := &testMetrics{}
+	fsm.SetMetrics(m)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+
+	expected := [][3]int{
+		{test_state_1, test_state_2, test_input_1},
+		{test_state_2, test_state_1, test_input_2},
+	}
+	if len(m.transitions) != 2 || m.transitions[0] != expected[0] || m.transitions[1] != expected[1] {
+		t.Errorf("Wrong observed transitions. (Expected: %v, Got: %v)", expected, m.transitions)
+	}
+	if m.actions != 2 {
+		t.Errorf("Wrong number of action durations. (Expected: %v, Got: %v)", 2, m.actions)
+	}
+}
+
+func TestSpinObserver(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	type observation struct {
+		start int
+		in    Input
+		hops  int
+	}
+	var observed []observation
+	fsm.SetSpinObserver(func(start int, in Input, hops int, d time.Duration) {
+		observed = append(observed, observation{start, in, hops})
+	})
+
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if _, err := fsm.Spin(ctx, test_input_2); err == nil {
+		t.Fatalf("FSM didn't error when spun with invalid input.")
+	}
+
+	expected := []observation{{test_state_1, test_input_1, 2}, {test_state_1, test_input_2, 0}}
+	if len(observed) != len(expected) || observed[0] != expected[0] || observed[1] != expected[1] {
+		t.Errorf("Wrong spins observed. (Expected: %v, Got: %v)", expected, observed)
+	}
+}
+
+func TestRejectHandler(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	var rejected [][2]int
+	fsm.SetRejectHandler(func(state int, in Input) {
+		rejected = append(rejected, [2]int{state, int(in)})
+	})
+
+	_, err = fsm.Spin(ctx, test_input_2)
+	switch err.(type) {
+	case InvalidInputError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	if len(rejected) != 1 || rejected[0] != [2]int{test_state_1, test_input_2} {
+		t.Errorf("Wrong rejections observed. (Expected: %v, Got: %v)", [][2]int{{test_state_1, test_input_2}}, rejected)
+	}
+}
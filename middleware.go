@@ -0,0 +1,45 @@
+package fsm
+
+import (
+	"context"
+)
+
+// A Middleware wraps the action of every transition, for cross-cutting concerns such as logging or tracing.
+// It receives the action to run next and returns the one Spin runs instead,
+// which decides whether and when to call next.
+type Middleware func(next Action) Action
+
+// Use registers mw to wrap the action of every transition, including NO_ACTION and ActionE actions.
+// Middleware wrap in the order they're registered: the first one is the outermost,
+// so it runs first and sees the result of all the others.
+// An error returned by an ActionE isn't visible to middleware, but still reaches Spin.
+// Middleware run with the lock held, so they must not call methods of the FSM.
+// This method is thread-safe.
+func (f *FSM) Use(mw Middleware) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.middleware = append(f.middleware, mw)
+}
+
+// withMiddleware wraps action in the registered middleware.
+// The caller must hold the lock.
+func (f *FSM) withMiddleware(action ActionE) ActionE {
+	if len(f.middleware) == 0 {
+		return action
+	}
+
+	return func(ctx context.Context) (context.Context, Input, error) {
+		var err error
+		wrapped := func(ctx context.Context) (context.Context, Input) {
+			var out Input
+			ctx, out, err = action(ctx)
+			return ctx, out
+		}
+		for i := len(f.middleware) - 1; i >= 0; i-- {
+			wrapped = f.middleware[i](wrapped)
+		}
+		ctx, out := wrapped(ctx)
+		return ctx, out, err
+	}
+}
@@ -0,0 +1,79 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	failure := errors.New("boom")
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: func(ctx context.Context) (context.Context, Input) {
+				calls = append(calls, "action")
+				return ctx, NO_INPUT
+			}},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, ActionE: func(ctx context.Context) (context.Context, Input, error) {
+				return ctx, NO_INPUT, failure
+			}},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	tracer := func(name string) Middleware {
+		return func(next Action) Action {
+			return func(ctx context.Context) (context.Context, Input) {
+				calls = append(calls, name+" before")
+				ctx, out := next(ctx)
+				calls = append(calls, name+" after")
+				return ctx, out
+			}
+		}
+	}
+	fsm.Use(tracer("outer"))
+	fsm.Use(tracer("inner"))
+
+	if _, err := fsm.Spin(ctx, test_input_1); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []string{"outer before", "inner before", "action", "inner after", "outer after"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Wrong calls. (Expected: %v, Got: %v)", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Wrong calls. (Expected: %v, Got: %v)", expected, calls)
+			break
+		}
+	}
+
+	// Errors of ActionE actions still reach Spin through the middleware.
+	_, err = fsm.Spin(ctx, test_input_1)
+	switch e := err.(type) {
+	case ActionError:
+		if e.Err != failure {
+			t.Errorf("Wrong action error. (Expected: %v, Got: %v)", failure, e.Err)
+		}
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Errorf("Wrong error type: %T (%v)", err, err)
+	}
+	if fsm.Current() != test_state_2 {
+		t.Errorf("Wrong state after failed action. (Expected: %v, Got: %v)", test_state_2, fsm.Current())
+	}
+}
@@ -0,0 +1,117 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RejectedByAllRegionsError indicates that no region of a ParallelFSM accepted an input.
+// Errs holds the rejection of every region, in region order.
+type RejectedByAllRegionsError struct {
+	Input Input
+	Errs  []error
+}
+
+func (err RejectedByAllRegionsError) Error() string {
+	return fmt.Sprintf("input rejected by all regions.  (Input: %v, Errors: %v)", err.Input, err.Errs)
+}
+
+// ParallelFSM runs several FSMs as orthogonal regions, which share their inputs but advance independently.
+type ParallelFSM struct {
+	sync.RWMutex
+	regions []*FSM
+}
+
+// DefineParallel combines FSMs into a ParallelFSM, in the given region order.
+// Will return an error if no regions are given, or if a region is nil or given twice.
+func DefineParallel(regions ...*FSM) (*ParallelFSM, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("attempt to define parallel FSM with no regions")
+	}
+	seen := make(map[*FSM]bool, len(regions))
+	for i, r := range regions {
+		if r == nil {
+			return nil, fmt.Errorf("parallel FSM region is nil.  (Region: %v)", i)
+		}
+		if seen[r] {
+			return nil, fmt.Errorf("parallel FSM region given twice.  (Region: %v)", i)
+		}
+		seen[r] = true
+	}
+
+	return &ParallelFSM{regions: append([]*FSM(nil), regions...)}, nil
+}
+
+// Spin spins every region with the input, in region order.
+// The context returned by each region is passed on to the next one, and the last one is returned.
+//
+// A region which rejects the input, with an InvalidInputError or a GuardRejectedError before making any hop,
+// stays where it is while the others advance; Spin only returns an error, a RejectedByAllRegionsError,
+// if every region rejects it. Any other error from a region, including the rejection of an input chained
+// by an action once the region has moved, is returned right away, leaving the regions before it advanced
+// and the regions after it untouched.
+// Spins of a ParallelFSM don't interleave, but each region can still be spun directly on its own.
+// This method is thread-safe.
+func (p *ParallelFSM) Spin(ctx context.Context, in Input) (context.Context, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	var rejections []error
+	for _, r := range p.regions {
+		next, hops, err := r.SpinVerbose(ctx, in)
+		if err != nil && regionRejected(hops, err) {
+			rejections = append(rejections, err)
+			continue
+		}
+		if err != nil {
+			return next, err
+		}
+		ctx = next
+	}
+
+	if len(rejections) == len(p.regions) {
+		return ctx, RejectedByAllRegionsError{in, rejections}
+	}
+	return ctx, nil
+}
+
+// regionRejected reports whether a region failed to spin because it rejected the input itself,
+// rather than partway through an action chain.
+func regionRejected(hops []Transition, err error) bool {
+	if len(hops) > 0 {
+		return false
+	}
+	switch e := err.(type) {
+	case InvalidInputError:
+		return e.Source == EXTERNAL_INPUT
+	case GuardRejectedError:
+		return true
+	}
+	return false
+}
+
+// Current returns the current state of every region, in region order.
+// This method is thread-safe.
+func (p *ParallelFSM) Current() []int {
+	p.RLock()
+	defer p.RUnlock()
+
+	current := make([]int, len(p.regions))
+	for i, r := range p.regions {
+		current[i] = r.Current()
+	}
+	return current
+}
+
+// Region returns the FSM of the i-th region, or nil if there is no such region.
+// This method is thread-safe.
+func (p *ParallelFSM) Region(i int) *FSM {
+	p.RLock()
+	defer p.RUnlock()
+
+	if i < 0 || i >= len(p.regions) {
+		return nil
+	}
+	return p.regions[i]
+}
@@ -0,0 +1,113 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	ctx := context.Background()
+
+	// A connection region toggled by test_input_1, and an auth region advanced by test_input_1 and test_input_2.
+	conn, err := Define(
+		State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_2}}},
+		State{Index: test_state_2, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_1}}},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	auth, err := Define(
+		State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_2}}},
+		State{Index: test_state_2, Outcomes: map[Input]Outcome{test_input_2: {State: test_state_3}}},
+		State{Index: test_state_3},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if _, err := DefineParallel(); err == nil {
+		t.Errorf("Didn't error defining parallel FSM with no regions.")
+	}
+	if _, err := DefineParallel(conn, conn); err == nil {
+		t.Errorf("Didn't error defining parallel FSM with a duplicate region.")
+	}
+
+	p, err := DefineParallel(conn, auth)
+	if err != nil {
+		t.Fatal("Failed to define parallel FSM: ", err)
+	}
+
+	steps := []struct {
+		in       Input
+		expected []int
+	}{
+		{test_input_1, []int{test_state_2, test_state_2}},
+		// Rejected by the connection region only.
+		{test_input_2, []int{test_state_2, test_state_3}},
+		// Rejected by the auth region only.
+		{test_input_1, []int{test_state_1, test_state_3}},
+	}
+	for _, step := range steps {
+		if _, err := p.Spin(ctx, step.in); err != nil {
+			t.Fatal(err.Error())
+		}
+		current := p.Current()
+		if current[0] != step.expected[0] || current[1] != step.expected[1] {
+			t.Errorf("Wrong region states. (Expected: %v, Got: %v)", step.expected, current)
+		}
+	}
+
+	_, err = p.Spin(ctx, test_input_3)
+	switch e := err.(type) {
+	case RejectedByAllRegionsError:
+		if len(e.Errs) != 2 {
+			t.Errorf("Wrong number of rejections. (Expected: %v, Got: %v)", 2, len(e.Errs))
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	if p.Region(1) != auth || p.Region(2) != nil {
+		t.Errorf("Wrong regions returned.")
+	}
+}
+
+func TestParallelChainRejected(t *testing.T) {
+	ctx := context.Background()
+
+	// Region a moves to state 2, from where the chained input is rejected.
+	a, err := Define(
+		State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_2,
+			Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }}}},
+		State{Index: test_state_2},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	b, err := Define(
+		State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: {State: test_state_2}}},
+		State{Index: test_state_2},
+	)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	p, err := DefineParallel(a, b)
+	if err != nil {
+		t.Fatal("Failed to define parallel FSM: ", err)
+	}
+
+	_, err = p.Spin(ctx, test_input_1)
+	switch e := err.(type) {
+	case InvalidInputError:
+		if e.Source != ACTION_INPUT {
+			t.Errorf("Wrong input source. (Expected: %v, Got: %v)", ACTION_INPUT, e.Source)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+	if b.Current() != test_state_1 {
+		t.Errorf("Region after the failing one moved. (Expected: %v, Got: %v)", test_state_1, b.Current())
+	}
+}
@@ -0,0 +1,36 @@
+package fsm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// stateVersion tags the format written by MarshalState.
+const stateVersion = 1
+
+// persistedState is the runtime position of an FSM as written by MarshalState.
+type persistedState struct {
+	Version int `json:"version"`
+	State   int `json:"state"`
+}
+
+// MarshalState encodes the current position of the FSM, so it can later be restored with RestoreState.
+// Only the current state is saved, not the definition.
+// This method is thread-safe.
+func (f *FSM) MarshalState() ([]byte, error) {
+	return json.Marshal(persistedState{Version: stateVersion, State: f.Current()})
+}
+
+// RestoreState moves the FSM to a position previously saved by MarshalState.
+// Will return an ImpossibleStateError if the saved state isn't part of the FSM definition.
+// This method is thread-safe.
+func (f *FSM) RestoreState(data []byte) error {
+	var p persistedState
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Version != stateVersion {
+		return fmt.Errorf("unsupported FSM state version: %d", p.Version)
+	}
+	return f.SetState(p.State)
+}
@@ -0,0 +1,50 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRestoreState(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	data, err := fsm.MarshalState()
+	if err != nil {
+		t.Fatal("Failed to marshal state: ", err)
+	}
+
+	restored := fsm.Clone()
+	if err := restored.RestoreState(data); err != nil {
+		t.Fatal("Failed to restore state: ", err)
+	}
+	if restored.Current() != test_state_2 {
+		t.Errorf("Restored FSM in wrong state. (Expected: %v, Got: %v)", test_state_2, restored.Current())
+	}
+
+	err = restored.RestoreState([]byte(`{"version": 1, "state": 7}`))
+	switch err.(type) {
+	case ImpossibleStateError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
@@ -0,0 +1,65 @@
+package fsm
+
+// A Pool hands out FSMs cloned from a template, and keeps returned ones for reuse,
+// so request-scoped machines don't have to be defined or cloned for every request.
+// A Pool is safe for concurrent use.
+type Pool struct {
+	template *FSM
+	idle     chan *FSM
+}
+
+// NewPool returns a Pool of clones of template, keeping at most size of them idle.
+// A size of zero or less keeps none, so every Get clones the template.
+// Changes made to template later are picked up by new clones only.
+func NewPool(template *FSM, size int) *Pool {
+	if size < 0 {
+		size = 0
+	}
+	return &Pool{template: template, idle: make(chan *FSM, size)}
+}
+
+// Get returns an FSM in the initial state of the template, either an idle one or a new clone. It never blocks.
+// This method is thread-safe.
+func (p *Pool) Get() *FSM {
+	select {
+	case f := <-p.idle:
+		return f
+	default:
+		return p.template.Clone()
+	}
+}
+
+// Put returns an FSM obtained from Get to the condition of a fresh clone and keeps it for a later Get,
+// or drops it if the pool is full. The FSM is moved back to its initial state without running any hooks,
+// its timers are stopped until Start is called again, its subscribers are dropped, their channels being closed,
+// and its history, undo stack, coverage and pause are cleared. Visited states start over from the initial state.
+// Other settings changed on it are kept, so a caller changing them should undo the changes
+// before returning the FSM, or not return it at all.
+// The FSM must not be used after Put.
+// This method is thread-safe.
+func (p *Pool) Put(f *FSM) {
+	f.recycle()
+	select {
+	case p.idle <- f:
+	default:
+	}
+}
+
+// recycle returns the FSM to the condition of a fresh clone, as described by Put.
+// This method is thread-safe.
+func (f *FSM) recycle() {
+	f.Lock()
+	defer f.Unlock()
+
+	for _, sub := range f.subs {
+		close(sub)
+	}
+	f.subs, f.history, f.undo, f.pending = nil, nil, nil, nil
+	f.coverage, f.done = nil, nil
+	f.started, f.timersStopped, f.paused, f.lastChanged = false, false, false, false
+	f.current = f.initial
+	if f.visited != nil {
+		f.visited = map[int]bool{f.initial: true}
+	}
+	f.armTimeout()
+}
@@ -0,0 +1,168 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	template, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	pool := NewPool(template, 2)
+
+	a := pool.Get()
+	if a == template {
+		t.Fatal("Pool handed out the template")
+	}
+	assertState(t, ctx, a, test_input_1, test_state_2)
+	if template.Current() != test_state_1 {
+		t.Errorf("Template moved with its clone. (Expected: %v, Got: %v)", test_state_1, template.Current())
+	}
+
+	// A returned FSM is reused, reset.
+	pool.Put(a)
+	if b := pool.Get(); b != a || b.Current() != test_state_1 {
+		t.Errorf("Returned FSM not reused in its initial state. (Expected: %v, Got: %v)", test_state_1, b.Current())
+	}
+
+	// At most two are kept.
+	fsms := []*FSM{pool.Get(), pool.Get(), pool.Get()}
+	for _, f := range fsms {
+		pool.Put(f)
+	}
+	if len(pool.idle) != 2 {
+		t.Errorf("Wrong number of idle FSMs. (Expected: 2, Got: %v)", len(pool.idle))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := pool.Get()
+			if _, err := f.Spin(ctx, test_input_1); err != nil {
+				t.Error(err.Error())
+			}
+			pool.Put(f)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPoolSettings(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	template, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	template.SetFinalStates(test_state_2)
+	template.EnableHistory(4)
+	template.EnableUndo(2)
+	pool := NewPool(template, 1)
+
+	// Every FSM handed out has the settings of the template, whether new or reused.
+	for i := 0; i < 2; i++ {
+		f := pool.Get()
+		assertState(t, ctx, f, test_input_1, test_state_2)
+		if !f.IsDone() {
+			t.Errorf("Pooled FSM not done in final state.")
+		}
+		if len(f.History()) == 0 {
+			t.Errorf("Pooled FSM didn't record history.")
+		}
+		if err := f.Undo(); err != nil {
+			t.Errorf("Pooled FSM didn't undo: %v", err)
+		}
+		pool.Put(f)
+	}
+}
+
+func TestPoolRecycle(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+		Timeout: &StateTimeout{Duration: 10 * time.Millisecond, Input: test_input_1},
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	template, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	template.EnableHistory(4)
+	template.EnableUndo(2)
+	pool := NewPool(template, 1)
+
+	a := pool.Get()
+	if _, err := a.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sub := a.Subscribe()
+	a.Pause()
+	pool.Put(a)
+
+	// An idle FSM doesn't time out by itself.
+	time.Sleep(30 * time.Millisecond)
+	b := pool.Get()
+	if b != a {
+		t.Fatal("Returned FSM not reused")
+	}
+	if b.Current() != test_state_1 {
+		t.Errorf("Idle FSM moved. (Expected: %v, Got: %v)", test_state_1, b.Current())
+	}
+	if _, ok := <-sub; ok {
+		t.Errorf("Subscriber of the previous user still open.")
+	}
+	if b.IsPaused() || len(b.History()) != 0 || b.Undo() == nil {
+		t.Errorf("Returned FSM not cleared. (Paused: %v, History: %v)", b.IsPaused(), b.History())
+	}
+
+	// Timers work again once the new user starts the FSM.
+	if _, err := b.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for b.Current() != test_state_2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if b.Current() != test_state_2 {
+		t.Errorf("Timeout didn't fire after Start. (Expected: %v, Got: %v)", test_state_2, b.Current())
+	}
+	b.StopTimers()
+}
@@ -0,0 +1,59 @@
+package fsm
+
+import (
+	"sync"
+)
+
+// registry holds the actions registered by name with RegisterAction.
+var registry = struct {
+	sync.RWMutex
+	actions map[string]Action
+}{actions: map[string]Action{}}
+
+// RegisterAction makes an action available by name to Outcomes which set ActionName,
+// and to definitions loaded from JSON which don't provide it themselves.
+// Registering a name again replaces its action, and a nil action removes it.
+// This function is thread-safe.
+func RegisterAction(name string, a Action) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if a == nil {
+		delete(registry.actions, name)
+		return
+	}
+	registry.actions[name] = a
+}
+
+// resolveActions returns the state with the Action of every Outcome which only sets an ActionName
+// looked up in the registry.
+// The Outcomes map is copied before it is changed, so the caller's map is left alone.
+// Will return an UnknownActionError if a name isn't registered.
+func resolveActions(s State) (State, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	var outcomes map[Input]Outcome
+	for _, in := range sortedInputs(s.Outcomes) {
+		do := s.Outcomes[in]
+		if do.ActionName == "" || do.Action != nil || do.ActionE != nil {
+			continue
+		}
+		a, ok := registry.actions[do.ActionName]
+		if !ok {
+			return s, UnknownActionError(do.ActionName)
+		}
+		if outcomes == nil {
+			outcomes = make(map[Input]Outcome, len(s.Outcomes))
+			for k, v := range s.Outcomes {
+				outcomes[k] = v
+			}
+		}
+		do.Action = a
+		outcomes[in] = do
+	}
+	if outcomes != nil {
+		s.Outcomes = outcomes
+	}
+	return s, nil
+}
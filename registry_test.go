@@ -0,0 +1,63 @@
+package fsm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRegisterAction(t *testing.T) {
+	ctx := context.Background()
+
+	hits := 0
+	RegisterAction("registry_test_hit", func(ctx context.Context) (context.Context, Input) {
+		hits++
+		return ctx, NO_INPUT
+	})
+	defer RegisterAction("registry_test_hit", nil)
+
+	outcomes := map[Input]Outcome{
+		test_input_1: Outcome{State: test_state_2, ActionName: "registry_test_hit"},
+	}
+	state1 := State{
+		Index:    test_state_1,
+		Outcomes: outcomes,
+	}
+	state2 := State{
+		Index: test_state_2,
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if outcomes[test_input_1].Action != nil {
+		t.Errorf("Define changed the caller's Outcomes.")
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if hits != 1 {
+		t.Errorf("Didn't hit registered action.")
+	}
+
+	// The JSON loader falls back to the registry.
+	fsm, err = DefineFromJSON(strings.NewReader(`{"states": [{"index": 0, "outcomes": [{"input": 0, "state": 0, "action": "registry_test_hit"}]}]}`))
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	if hits != 2 {
+		t.Errorf("Didn't hit registered action from JSON.")
+	}
+
+	state1.Outcomes = map[Input]Outcome{
+		test_input_1: Outcome{State: test_state_2, ActionName: "registry_test_missing"},
+	}
+	_, err = Define(state1, state2)
+	switch err.(type) {
+	case UnknownActionError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
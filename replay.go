@@ -0,0 +1,61 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplayMismatchError indicates that a replayed FSM diverged from the recorded transitions.
+// Index is the position in the log of the first transition which differs.
+// A zero Expected means the FSM made more hops than were recorded, and a zero Got that it made fewer.
+type ReplayMismatchError struct {
+	Index    int
+	Expected Transition
+	Got      Transition
+}
+
+func (err ReplayMismatchError) Error() string {
+	return fmt.Sprintf("replay diverged from log.  (Index: %v, Expected: %v -> %v on %v, Got: %v -> %v on %v)",
+		err.Index, err.Expected.From, err.Expected.To, err.Expected.Input, err.Got.From, err.Got.To, err.Got.Input)
+}
+
+// Replay checks that the FSM still makes the recorded transitions, e.g. as returned by History or SpinVerbose.
+// It resets the FSM to its initial state, then spins it with the input of each recorded transition
+// which wasn't chained by an action, comparing every hop with the log, Time aside.
+// Returns a ReplayMismatchError at the first divergence, or the error of a Spin which fails.
+// Internal transitions are made but not recorded by History, so their hops have to be left out
+// of a log recorded that way. The hops of the replay are published and recorded like any others,
+// and the FSM is left where the replay stopped.
+//
+// Actions and hooks run during the replay as they would in a normal Spin, so any side effects they have happen again:
+// replay against a machine wired to fakes, or a Clone with side-effect free actions.
+// This method is thread-safe.
+func (f *FSM) Replay(ctx context.Context, log []Transition) error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.reset()
+
+	for k := 0; k < len(log); {
+		var hops []Transition
+		var err error
+		ctx, hops, err = f.spin(ctx, log[k].Input, true)
+		for _, got := range hops {
+			if k == len(log) {
+				return ReplayMismatchError{k, Transition{}, got}
+			}
+			expected := log[k]
+			if got.From != expected.From || got.To != expected.To || got.Input != expected.Input {
+				return ReplayMismatchError{k, expected, got}
+			}
+			k++
+		}
+		if err != nil {
+			return err
+		}
+		if len(hops) == 0 {
+			return ReplayMismatchError{k, log[k], Transition{}}
+		}
+	}
+	return nil
+}
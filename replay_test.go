@@ -0,0 +1,67 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2,
+				Action: func(ctx context.Context) (context.Context, Input) { return ctx, test_input_2 }},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_3: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.EnableHistory(10)
+
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+	assertState(t, ctx, fsm, test_input_3, test_state_1)
+	log := fsm.History()
+
+	if err := fsm.Replay(ctx, log); err != nil {
+		t.Fatal(err.Error())
+	}
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Wrong state after replay. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+
+	// A refactored machine where state 3 goes back to state 2 instead.
+	state3.Outcomes = map[Input]Outcome{
+		test_input_3: Outcome{State: test_state_2, Action: NO_ACTION},
+	}
+	refactored, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	err = refactored.Replay(ctx, log)
+	switch e := err.(type) {
+	case ReplayMismatchError:
+		if e.Index != 2 || e.Got.To != test_state_2 {
+			t.Errorf("Wrong mismatch details: %+v", e)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
@@ -0,0 +1,27 @@
+package fsm
+
+// A Snapshot captures the state of an FSM at one point in time, for debugging.
+type Snapshot struct {
+	Current     int
+	Initial     int
+	ValidInputs []Input
+	History     []Transition
+	Terminal    bool
+}
+
+// Snapshot returns the current state, initial state, valid inputs, recorded history and whether the FSM is terminal,
+// all read under a single lock acquisition, so they are consistent with each other
+// even while other goroutines spin the FSM.
+// This method is thread-safe.
+func (f *FSM) Snapshot() Snapshot {
+	f.RLock()
+	defer f.RUnlock()
+
+	return Snapshot{
+		Current:     f.current,
+		Initial:     f.initial,
+		ValidInputs: f.validInputs(),
+		History:     append([]Transition(nil), f.history...),
+		Terminal:    len(f.states[f.current].Outcomes) == 0,
+	}
+}
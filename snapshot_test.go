@@ -0,0 +1,50 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_3: Outcome{State: test_state_3, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.EnableHistory(10)
+
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	snap := fsm.Snapshot()
+	if snap.Current != test_state_2 || snap.Initial != test_state_1 || snap.Terminal {
+		t.Errorf("Wrong snapshot: %+v", snap)
+	}
+	if len(snap.ValidInputs) != 2 || snap.ValidInputs[0] != test_input_2 || snap.ValidInputs[1] != test_input_3 {
+		t.Errorf("Wrong valid inputs. (Expected: %v, Got: %v)", []Input{test_input_2, test_input_3}, snap.ValidInputs)
+	}
+	if len(snap.History) != 1 || snap.History[0].To != test_state_2 {
+		t.Errorf("Wrong history: %v", snap.History)
+	}
+
+	assertState(t, ctx, fsm, test_input_3, test_state_3)
+	if snap = fsm.Snapshot(); !snap.Terminal || len(snap.ValidInputs) != 0 || len(snap.History) != 2 {
+		t.Errorf("Wrong snapshot: %+v", snap)
+	}
+}
@@ -0,0 +1,49 @@
+package fsm
+
+import (
+	"sort"
+)
+
+// TagState attaches tags to a state, on top of any it already has, e.g. "authenticated" or "transient".
+// Tags are metadata for the caller: the FSM itself doesn't interpret them.
+// Tags on a state which isn't part of the FSM definition are kept, but ignored by StatesWithTag.
+// This method is thread-safe.
+func (f *FSM) TagState(index int, tags ...string) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.tags == nil {
+		f.tags = map[int]map[string]bool{}
+	}
+	if f.tags[index] == nil {
+		f.tags[index] = map[string]bool{}
+	}
+	for _, tag := range tags {
+		f.tags[index][tag] = true
+	}
+}
+
+// StatesWithTag returns the indices of the states tagged with tag, sorted ascending.
+// This method is thread-safe.
+func (f *FSM) StatesWithTag(tag string) []int {
+	f.RLock()
+	defer f.RUnlock()
+
+	indices := []int{}
+	for index, tags := range f.tags {
+		if _, ok := f.states[index]; ok && tags[tag] {
+			indices = append(indices, index)
+		}
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// CurrentHasTag reports whether the state the FSM is currently in is tagged with tag.
+// This method is thread-safe.
+func (f *FSM) CurrentHasTag(tag string) bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.tags[f.current][tag]
+}
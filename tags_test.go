@@ -0,0 +1,57 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.TagState(test_state_3, "error")
+	fsm.TagState(test_state_2, "authenticated")
+	fsm.TagState(test_state_3, "authenticated", "final")
+	fsm.TagState(7, "authenticated")
+
+	got := fsm.StatesWithTag("authenticated")
+	if len(got) != 2 || got[0] != test_state_2 || got[1] != test_state_3 {
+		t.Errorf("Wrong tagged states. (Expected: %v, Got: %v)", []int{test_state_2, test_state_3}, got)
+	}
+	if got := fsm.StatesWithTag("unknown"); len(got) != 0 {
+		t.Errorf("Wrong tagged states. (Expected: [], Got: %v)", got)
+	}
+
+	if fsm.CurrentHasTag("authenticated") {
+		t.Error("Untagged current state reported as tagged")
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if !fsm.CurrentHasTag("authenticated") || fsm.CurrentHasTag("error") {
+		t.Error("Wrong tags for the current state")
+	}
+
+	clone := fsm.Clone()
+	fsm.TagState(test_state_1, "error")
+	if got := clone.StatesWithTag("error"); len(got) != 1 || got[0] != test_state_3 {
+		t.Errorf("Clone shares tags with the original. (Expected: %v, Got: %v)", []int{test_state_3}, got)
+	}
+}
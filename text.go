@@ -0,0 +1,68 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String renders the FSM definition as a plain text transition table, one state per line followed by its Outcomes.
+// States and inputs are shown by index, along with their names set by SetLogger when present,
+// and the initial and current states are marked.
+// States and inputs are sorted ascending, so the output is deterministic.
+// This method is thread-safe.
+func (f *FSM) String() string {
+	f.RLock()
+	defer f.RUnlock()
+
+	var b strings.Builder
+	for _, index := range f.sortedStates() {
+		b.WriteString(f.stateText(index))
+		var marks []string
+		if index == f.initial {
+			marks = append(marks, "initial")
+		}
+		if index == f.current {
+			marks = append(marks, "current")
+		}
+		if len(marks) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(marks, ", "))
+		}
+		b.WriteString("\n")
+
+		s := f.states[index]
+		for _, in := range sortedInputs(s.Outcomes) {
+			fmt.Fprintf(&b, "    %s -> %s\n", f.inputText(in), f.stateText(s.Outcomes[in].target(index)))
+		}
+	}
+	if f.fallback != nil {
+		fmt.Fprintf(&b, "default -> %s\n", f.stateText(f.fallback.State))
+	}
+
+	return b.String()
+}
+
+// stateText returns the index of a state in brackets, followed by its name if it has one.
+func (f *FSM) stateText(index int) string {
+	if index == CHOICE {
+		return "<choice>"
+	}
+	if index == SAME_STATE {
+		return "<same>"
+	}
+	if name := f.getStateName(index); name != "" {
+		return fmt.Sprintf("[%d] %s", index, name)
+	}
+	return fmt.Sprintf("[%d]", index)
+}
+
+// inputText returns the value of an input in brackets, followed by its name if it has one.
+func (f *FSM) inputText(in Input) string {
+	text := fmt.Sprintf("[%d]", int(in))
+	if in == ANY_INPUT {
+		text = "[*]"
+	}
+	if name := f.getInputName(in); name != "" {
+		return text + " " + name
+	}
+	return text
+}
@@ -0,0 +1,46 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			ANY_INPUT: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	fsm.SetLogger(nil, map[int]string{test_state_1: "idle", test_state_2: "busy"}, map[Input]string{test_input_1: "go"})
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	expected := `[0] idle (initial)
+    [0] go -> [1] busy
+    [1] -> [2]
+[1] busy (current)
+    [*] -> [0] idle
+[2]
+`
+	if got := fmt.Sprint(fsm); got != expected {
+		t.Errorf("Wrong text rendering.\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+}
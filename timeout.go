@@ -0,0 +1,74 @@
+package fsm
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// A StateTimeout makes an FSM spin Input by itself once it has stayed Duration in a state,
+// modelling a UML time event.
+//
+// The timer of a state is armed whenever the FSM enters it after Start has been called,
+// whether by Spin, SetState, Reset or Undo, and is cancelled as soon as the FSM leaves it.
+// Internal transitions neither leave nor re-enter the state, so they don't restart its timer.
+// When the timer fires, Input is spun from the timer's own goroutine with a background context,
+// taking the FSM lock like any other Spin; it is dropped if the FSM moved in the meantime.
+// Errors from that Spin have no caller to go to, so they are logged.
+// StopTimers cancels the pending timer and keeps any more from being armed.
+type StateTimeout struct {
+	Duration time.Duration
+	Input    Input
+}
+
+// StopTimers cancels the timer of the current state, if any, and keeps timers from being armed from then on.
+// Call it when done with an FSM with StateTimeouts, so no timer goroutine keeps spinning it.
+// This method is thread-safe.
+func (f *FSM) StopTimers() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.timersStopped = true
+	f.armTimeout()
+}
+
+// armTimeout cancels the pending timer, and arms the one of the current state if it has a Timeout.
+// The caller must hold the lock.
+func (f *FSM) armTimeout() {
+	f.timerGen++
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	if !f.started || f.timersStopped {
+		return
+	}
+
+	s, ok := f.states[f.current]
+	if !ok || s.Timeout == nil {
+		return
+	}
+	gen, in := f.timerGen, s.Timeout.Input
+	f.timer = time.AfterFunc(s.Timeout.Duration, func() { f.fireTimeout(gen, in) })
+}
+
+// fireTimeout spins the timeout input of a state, unless the FSM left it since the timer was armed.
+func (f *FSM) fireTimeout(gen uint64, in Input) {
+	f.Lock()
+	defer f.Unlock()
+
+	if gen != f.timerGen {
+		return
+	}
+	f.timer = nil
+
+	if f.logs(logrus.TraceLevel) {
+		f.logEntry(f.current, in).Tracef("FSM: timeout in state [%d][%s], spin input [%d][%s]", f.current, f.getStateName(f.current), in, f.getInputName(in))
+	}
+	if _, _, err := f.spinChain(context.Background(), in, false); err != nil {
+		if f.logs(logrus.ErrorLevel) {
+			f.logEntry(f.current, in).WithError(err).Errorf("FSM: timeout input [%d][%s] failed: %v", in, f.getInputName(in), err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStateTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+			test_input_2: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+		Timeout: &StateTimeout{Duration: 10 * time.Millisecond, Input: test_input_1},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+			test_input_3: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+		Timeout: &StateTimeout{Duration: 50 * time.Millisecond, Input: test_input_3},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	defer fsm.StopTimers()
+
+	// Timers don't fire before Start.
+	time.Sleep(20 * time.Millisecond)
+	if fsm.Current() != test_state_1 {
+		t.Fatalf("Timeout fired before Start.")
+	}
+
+	ch := fsm.Subscribe()
+	if _, err := fsm.Start(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+	select {
+	case got := <-ch:
+		if got.From != test_state_1 || got.To != test_state_2 || got.Input != test_input_1 {
+			t.Errorf("Wrong timeout transition. (Expected: %v -> %v on %v, Got: %v -> %v on %v)", test_state_1, test_state_2, test_input_1, got.From, got.To, got.Input)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timeout didn't fire.")
+	}
+
+	// Leaving a state cancels its timer.
+	if err := fsm.SetState(test_state_3); err != nil {
+		t.Fatal(err.Error())
+	}
+	time.Sleep(80 * time.Millisecond)
+	if fsm.Current() != test_state_3 {
+		t.Errorf("Timeout of a state left fired. (Expected: %v, Got: %v)", test_state_3, fsm.Current())
+	}
+
+	// No timers are armed after StopTimers.
+	fsm.StopTimers()
+	if err := fsm.SetState(test_state_1); err != nil {
+		t.Fatal(err.Error())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if fsm.Current() != test_state_1 {
+		t.Errorf("Timeout fired after StopTimers. (Expected: %v, Got: %v)", test_state_1, fsm.Current())
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("Unexpected transition: %v -> %v on %v", got.From, got.To, got.Input)
+	default:
+	}
+}
@@ -0,0 +1,216 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// A TypedAction is the generic counterpart of Action.
+// It returns the next input to chain, and false when the chain should stop.
+type TypedAction[I comparable] func(context.Context) (context.Context, I, bool)
+
+// NoTypedAction is the generic counterpart of NO_ACTION.
+func NoTypedAction[I comparable](ctx context.Context) (context.Context, I, bool) {
+	var none I
+	return ctx, none, false
+}
+
+// A TypedOutcome is the generic counterpart of Outcome.
+type TypedOutcome[S comparable, I comparable] struct {
+	State  S
+	Action TypedAction[I]
+}
+
+// A TypedState is the generic counterpart of State.
+type TypedState[S comparable, I comparable] struct {
+	Index    S
+	Outcomes map[I]TypedOutcome[S, I]
+}
+
+// TypedFSM is a Finite State Machine whose states and inputs are distinct user-defined types,
+// so a state can never be passed where an input is expected.
+// It supports the core of FSM: defining, spinning with action chains, and querying or forcing the current state.
+// The int-based FSM remains the full-featured API, and isn't built on top of TypedFSM:
+// each has its own spin loop, so features added to FSM, such as guards, hooks or subscribers,
+// aren't available here. Errors are reported by the generic counterparts of the FSM errors.
+type TypedFSM[S comparable, I comparable] struct {
+	sync.RWMutex
+	states   map[S]TypedState[S, I]
+	initial  S
+	current  S
+	maxDepth int
+}
+
+// TypedInvalidInputError is the generic counterpart of InvalidInputError.
+type TypedInvalidInputError[S comparable, I comparable] struct {
+	StateIndex S
+	Input      I
+}
+
+func (err TypedInvalidInputError[S, I]) Error() string {
+	return fmt.Sprintf("input invalid in current state.  (State: %v, Input: %v)", err.StateIndex, err.Input)
+}
+
+// TypedImpossibleStateError is the generic counterpart of ImpossibleStateError.
+type TypedImpossibleStateError[S comparable] struct {
+	StateIndex S
+}
+
+func (err TypedImpossibleStateError[S]) Error() string {
+	return fmt.Sprintf("FSM in impossible state: %v", err.StateIndex)
+}
+
+// TypedChainLimitExceededError is the generic counterpart of ChainLimitExceededError.
+type TypedChainLimitExceededError[I comparable] struct {
+	Depth int
+	Input I
+}
+
+func (err TypedChainLimitExceededError[I]) Error() string {
+	return fmt.Sprintf("action chain exceeded maximum depth.  (Depth: %v, Input: %v)", err.Depth, err.Input)
+}
+
+// TypedInterruptedError is the generic counterpart of InterruptedError.
+type TypedInterruptedError[S comparable, I comparable] struct {
+	StateIndex S
+	Input      I
+	Err        error
+}
+
+func (err TypedInterruptedError[S, I]) Error() string {
+	return fmt.Sprintf("spin interrupted: %v.  (State: %v, Input: %v)", err.Err, err.StateIndex, err.Input)
+}
+
+// Unwrap returns the context error which interrupted the spin.
+func (err TypedInterruptedError[S, I]) Unwrap() error {
+	return err.Err
+}
+
+// TypedUndefinedTargetError is the generic counterpart of UndefinedTargetError.
+type TypedUndefinedTargetError[S comparable, I comparable] struct {
+	FromState S
+	Input     I
+	Target    S
+}
+
+func (err TypedUndefinedTargetError[S, I]) Error() string {
+	return fmt.Sprintf("outcome targets undefined state.  (State: %v, Input: %v, Target: %v)", err.FromState, err.Input, err.Target)
+}
+
+// TypedClashingStateError is the generic counterpart of ClashingStateError.
+type TypedClashingStateError[S comparable] struct {
+	StateIndex S
+}
+
+func (err TypedClashingStateError[S]) Error() string {
+	return fmt.Sprintf("attempt to define FSM with clashing states. Index: %v", err.StateIndex)
+}
+
+// DefineTyped defines a TypedFSM from a list of TypedStates.
+// The first state is the initial state.
+// Will return an EmptyDefinitionError if the list is empty, a TypedClashingStateError if a state is listed twice,
+// or a TypedUndefinedTargetError if an Outcome targets a state which isn't in the list.
+// Inputs can't be sorted, so when several Outcomes have undefined targets, any one of them may be reported.
+func DefineTyped[S comparable, I comparable](states ...TypedState[S, I]) (*TypedFSM[S, I], error) {
+	if len(states) == 0 {
+		return nil, EmptyDefinitionError{}
+	}
+
+	stateMap := map[S]TypedState[S, I]{}
+	for _, s := range states {
+		if _, ok := stateMap[s.Index]; ok {
+			return nil, TypedClashingStateError[S]{s.Index}
+		}
+		stateMap[s.Index] = s
+	}
+	for _, s := range states {
+		for in, do := range s.Outcomes {
+			if _, ok := stateMap[do.State]; !ok {
+				return nil, TypedUndefinedTargetError[S, I]{s.Index, in, do.State}
+			}
+		}
+	}
+
+	return &TypedFSM[S, I]{
+		states:   stateMap,
+		initial:  states[0].Index,
+		current:  states[0].Index,
+		maxDepth: DEFAULT_MAX_CHAIN_DEPTH,
+	}, nil
+}
+
+// Spin the TypedFSM one time, following any chained inputs returned by actions.
+// The context is checked before every hop, and a TypedInterruptedError is returned once it is done.
+// A TypedChainLimitExceededError is returned once the chain is longer than allowed by SetMaxChainDepth.
+// This method is thread-safe.
+func (f *TypedFSM[S, I]) Spin(ctx context.Context, in I) (context.Context, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	for i, more, depth := in, true, 1; more; depth++ {
+		if f.maxDepth > 0 && depth > f.maxDepth {
+			return ctx, TypedChainLimitExceededError[I]{f.maxDepth, i}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return ctx, TypedInterruptedError[S, I]{f.current, i, err}
+		}
+
+		s, ok := f.states[f.current]
+		if !ok {
+			return ctx, TypedImpossibleStateError[S]{f.current}
+		}
+
+		do, ok := s.Outcomes[i]
+		if !ok {
+			return ctx, TypedInvalidInputError[S, I]{f.current, i}
+		}
+
+		ctx, i, more = do.Action(ctx)
+		f.current = do.State
+	}
+
+	return ctx, nil
+}
+
+// SetMaxChainDepth limits the number of hops a single Spin may take through chained actions,
+// DEFAULT_MAX_CHAIN_DEPTH by default. A value of zero or less removes the limit.
+// This method is thread-safe.
+func (f *TypedFSM[S, I]) SetMaxChainDepth(n int) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.maxDepth = n
+}
+
+// Current returns the state the TypedFSM is currently in.
+// This method is thread-safe.
+func (f *TypedFSM[S, I]) Current() S {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.current
+}
+
+// SetState forces the TypedFSM into the given state without spinning.
+// This method is thread-safe.
+func (f *TypedFSM[S, I]) SetState(s S) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.states[s]; !ok {
+		return TypedImpossibleStateError[S]{s}
+	}
+	f.current = s
+	return nil
+}
+
+// Reset moves the TypedFSM back to its initial state.
+// This method is thread-safe.
+func (f *TypedFSM[S, I]) Reset() {
+	f.Lock()
+	defer f.Unlock()
+
+	f.current = f.initial
+}
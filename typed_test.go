@@ -0,0 +1,133 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+type testDoor string
+type testKey int
+
+const (
+	doorOpen   testDoor = "open"
+	doorClosed testDoor = "closed"
+	doorLocked testDoor = "locked"
+)
+
+const (
+	keyPush testKey = iota
+	keyPull
+	keyTurn
+)
+
+func TestTyped(t *testing.T) {
+	ctx := context.Background()
+
+	closed := TypedState[testDoor, testKey]{
+		Index: doorClosed,
+		Outcomes: map[testKey]TypedOutcome[testDoor, testKey]{
+			keyPull: {State: doorOpen, Action: NoTypedAction[testKey]},
+			keyTurn: {State: doorLocked, Action: NoTypedAction[testKey]},
+		},
+	}
+	open := TypedState[testDoor, testKey]{
+		Index: doorOpen,
+		Outcomes: map[testKey]TypedOutcome[testDoor, testKey]{
+			// Pushing an open door closes and locks it.
+			keyPush: {State: doorClosed, Action: func(ctx context.Context) (context.Context, testKey, bool) { return ctx, keyTurn, true }},
+		},
+	}
+	locked := TypedState[testDoor, testKey]{
+		Index: doorLocked,
+		Outcomes: map[testKey]TypedOutcome[testDoor, testKey]{
+			keyTurn: {State: doorClosed, Action: NoTypedAction[testKey]},
+		},
+	}
+
+	fsm, err := DefineTyped(closed, open, locked)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if _, err := fsm.Spin(ctx, keyPull); err != nil {
+		t.Fatal(err)
+	}
+	if fsm.Current() != doorOpen {
+		t.Errorf("FSM in wrong state. (Expected: %v, Got: %v)", doorOpen, fsm.Current())
+	}
+
+	if _, err := fsm.Spin(ctx, keyPush); err != nil {
+		t.Fatal(err)
+	}
+	if fsm.Current() != doorLocked {
+		t.Errorf("FSM in wrong state. (Expected: %v, Got: %v)", doorLocked, fsm.Current())
+	}
+
+	_, err = fsm.Spin(ctx, keyPull)
+	switch err.(type) {
+	case TypedInvalidInputError[testDoor, testKey]:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	// An endless chain is cut short.
+	loop := TypedState[testDoor, testKey]{
+		Index: doorOpen,
+		Outcomes: map[testKey]TypedOutcome[testDoor, testKey]{
+			keyPush: {State: doorOpen, Action: func(ctx context.Context) (context.Context, testKey, bool) { return ctx, keyPush, true }},
+		},
+	}
+	looping, err := DefineTyped(loop)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	looping.SetMaxChainDepth(3)
+	_, err = looping.Spin(ctx, keyPush)
+	switch e := err.(type) {
+	case TypedChainLimitExceededError[testKey]:
+		if e.Depth != 3 || e.Input != keyPush {
+			t.Errorf("Wrong error fields. (Expected: 3, %v, Got: %v, %v)", keyPush, e.Depth, e.Input)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	// A done context stops the spin before the first hop.
+	if _, err := fsm.Spin(ctx, keyTurn); err != nil {
+		t.Fatal(err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = fsm.Spin(cancelled, keyPull)
+	switch e := err.(type) {
+	case TypedInterruptedError[testDoor, testKey]:
+		if e.StateIndex != doorClosed || e.Err != context.Canceled {
+			t.Errorf("Wrong error fields. (Expected: %v, %v, Got: %v, %v)", doorClosed, context.Canceled, e.StateIndex, e.Err)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
+
+func TestTypedUndefinedTarget(t *testing.T) {
+	closed := TypedState[testDoor, testKey]{
+		Index: doorClosed,
+		Outcomes: map[testKey]TypedOutcome[testDoor, testKey]{
+			keyPull: {State: doorOpen, Action: NoTypedAction[testKey]},
+		},
+	}
+
+	_, err := DefineTyped(closed)
+	switch e := err.(type) {
+	case TypedUndefinedTargetError[testDoor, testKey]:
+		if e.FromState != doorClosed || e.Input != keyPull || e.Target != doorOpen {
+			t.Errorf("Wrong error fields: %+v", e)
+		}
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+}
@@ -0,0 +1,64 @@
+package fsm
+
+import (
+	"fmt"
+)
+
+// NothingToUndoError indicates that Undo was called with no previous state to return to.
+type NothingToUndoError int
+
+func (err NothingToUndoError) Error() string {
+	return fmt.Sprintf("nothing to undo.  (State: %v)", int(err))
+}
+
+// EnableUndo makes the FSM remember the states it left on its last depth hops, so Undo can return to them.
+// The oldest states are discarded first.
+// A depth of zero or less disables undo and forgets any remembered states.
+// This method is thread-safe.
+func (f *FSM) EnableUndo(depth int) {
+	f.Lock()
+	defer f.Unlock()
+
+	if depth <= 0 {
+		depth = 0
+	}
+	f.undoLen = depth
+	if len(f.undo) > depth {
+		f.undo = append([]int(nil), f.undo[len(f.undo)-depth:]...)
+	}
+}
+
+// Undo moves the FSM back to the state it was in before its last hop, as remembered since EnableUndo.
+// Every hop of an action chain is undone separately, while internal transitions aren't remembered at all.
+// Only the state position is reverted: no actions or hooks are run,
+// and any side effects of the actions performed on the way are not undone.
+// Will return a NothingToUndoError if there is no previous state to return to.
+// This method is thread-safe.
+func (f *FSM) Undo() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if len(f.undo) == 0 {
+		return NothingToUndoError(f.current)
+	}
+
+	prev := f.undo[len(f.undo)-1]
+	f.undo = f.undo[:len(f.undo)-1]
+
+	f.log.Tracef("FSM: undo to state [%d][%s]", prev, f.getStateName(prev))
+	f.current = prev
+	f.armTimeout()
+	return nil
+}
+
+// pushUndo remembers the state the FSM is leaving, if undo is enabled.
+func (f *FSM) pushUndo(state int) {
+	if f.undoLen == 0 {
+		return
+	}
+	if len(f.undo) == f.undoLen {
+		copy(f.undo, f.undo[1:])
+		f.undo = f.undo[:len(f.undo)-1]
+	}
+	f.undo = append(f.undo, state)
+}
@@ -0,0 +1,68 @@
+package fsm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_3, Action: NO_ACTION},
+		},
+	}
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	// Hops made before undo is enabled aren't remembered.
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	err = fsm.Undo()
+	switch err.(type) {
+	case NothingToUndoError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	fsm.EnableUndo(2)
+	assertState(t, ctx, fsm, test_input_1, test_state_3)
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+
+	// Only the last two states are remembered.
+	for _, expected := range []int{test_state_1, test_state_3} {
+		if err := fsm.Undo(); err != nil {
+			t.Fatal(err.Error())
+		}
+		if fsm.Current() != expected {
+			t.Errorf("Wrong state after undo. (Expected: %v, Got: %v)", expected, fsm.Current())
+		}
+	}
+	if err := fsm.Undo(); err == nil {
+		t.Errorf("Didn't error with nothing left to undo.")
+	}
+
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+	fsm.Reset()
+	if err := fsm.Undo(); err == nil {
+		t.Errorf("Didn't error undoing after reset.")
+	}
+}
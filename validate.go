@@ -0,0 +1,97 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Check is one of the structural checks run by Validate.
+// Checks are bit flags, so several can be combined with |.
+type Check int
+
+const (
+	// CheckEmptyDefinition reports an FSM without any states.
+	CheckEmptyDefinition Check = 1 << iota
+	// CheckUndefinedTargets reports Outcomes pointing to states which aren't part of the FSM definition,
+	// and weighted Outcomes with invalid weights.
+	CheckUndefinedTargets
+	// CheckUnreachableStates reports states which can never be reached from the initial state.
+	CheckUnreachableStates
+)
+
+// UnreachableStateError indicates that a state can never be reached from the initial state.
+type UnreachableStateError int
+
+func (err UnreachableStateError) Error() string {
+	return fmt.Sprintf("state can't be reached from the initial state.  (State: %d)", err)
+}
+
+// ValidationError gathers every problem found by Validate, in the order the checks were run.
+type ValidationError struct {
+	Errors []error
+}
+
+func (err ValidationError) Error() string {
+	msgs := make([]string, len(err.Errors))
+	for i, e := range err.Errors {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("FSM definition has %d problem(s): %s", len(err.Errors), strings.Join(msgs, "; "))
+}
+
+// SetValidationWarnings makes Validate log the problems found by the given checks as warnings
+// instead of returning them, replacing any checks set before. Pass 0 to make every check an error again.
+// This method is thread-safe.
+func (f *FSM) SetValidationWarnings(checks Check) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.warnChecks = checks
+}
+
+// Validate runs every structural check on the FSM definition:
+// that it has states, that every Outcome targets a state of the definition,
+// and that every state can be reached from the initial state.
+// Problems found by checks set with SetValidationWarnings are logged as warnings,
+// and the others are returned together in a ValidationError.
+// Returns nil when no check fails as an error.
+// This method is thread-safe.
+func (f *FSM) Validate() error {
+	f.RLock()
+	defer f.RUnlock()
+
+	var errs []error
+	report := func(c Check, err error) {
+		if f.warnChecks&c != 0 {
+			f.log.Warnf("FSM: %v", err)
+			return
+		}
+		errs = append(errs, err)
+	}
+
+	if len(f.states) == 0 {
+		report(CheckEmptyDefinition, EmptyDefinitionError{})
+	}
+
+	for _, index := range f.sortedStates() {
+		s := f.states[index]
+		for _, in := range sortedInputs(s.Outcomes) {
+			exists := func(index int) bool { return hasState(f.states, index) }
+			if err := checkTarget(index, in, s.Outcomes[in], exists); err != nil {
+				report(CheckUndefinedTargets, err)
+			}
+		}
+	}
+
+	reached := f.reachableFrom(f.initial)
+	for _, index := range f.sortedStates() {
+		if !reached[index] {
+			report(CheckUnreachableStates, UnreachableStateError(index))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return ValidationError{errs}
+}
@@ -0,0 +1,76 @@
+package fsm
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_2, Action: NO_ACTION},
+		},
+	}
+	state2 := State{
+		Index: test_state_2,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+
+	fsm, err := Define(state1, state2)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	if err := fsm.Validate(); err != nil {
+		t.Errorf("Well-formed FSM failed validation: %v", err)
+	}
+
+	// State 3 can't be reached, and the outcome map is edited behind Define's back.
+	state3 := State{
+		Index: test_state_3,
+		Outcomes: map[Input]Outcome{
+			test_input_1: Outcome{State: test_state_1, Action: NO_ACTION},
+		},
+	}
+	fsm, err = Define(state1, state2, state3)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	state2.Outcomes[test_input_2] = Outcome{State: 7, Action: NO_ACTION}
+
+	err = fsm.Validate()
+	switch e := err.(type) {
+	case ValidationError:
+		if len(e.Errors) != 2 {
+			t.Fatalf("Wrong number of problems. (Expected: 2, Got: %v)", e.Errors)
+		}
+		if e.Errors[0] != (UndefinedTargetError{test_state_2, test_input_2, 7}) {
+			t.Errorf("Wrong first problem: %v", e.Errors[0])
+		}
+		if e.Errors[1] != UnreachableStateError(test_state_3) {
+			t.Errorf("Wrong second problem: %v", e.Errors[1])
+		}
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Fatalf("Wrong error type: %T (%v)", err, err)
+	}
+
+	// Unreachable states are only warned about.
+	fsm.SetValidationWarnings(CheckUnreachableStates)
+	err = fsm.Validate()
+	if e, ok := err.(ValidationError); !ok || len(e.Errors) != 1 {
+		t.Errorf("Expected only the undefined target. (Got: %v)", err)
+	}
+
+	fsm.SetValidationWarnings(CheckUnreachableStates | CheckUndefinedTargets)
+	if err := fsm.Validate(); err != nil {
+		t.Errorf("Warnings returned as errors: %v", err)
+	}
+
+	empty := &FSM{log: quietLogger}
+	err = empty.Validate()
+	if e, ok := err.(ValidationError); !ok || e.Errors[0] != (EmptyDefinitionError{}) {
+		t.Errorf("Expected an EmptyDefinitionError. (Got: %v)", err)
+	}
+}
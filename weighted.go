@@ -0,0 +1,148 @@
+package fsm
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// A WeightedTarget is one of the states a weighted Outcome may move to, with its relative Weight.
+type WeightedTarget struct {
+	State  int
+	Weight float64
+}
+
+// InvalidWeightError indicates that the weights of a weighted Outcome can't be drawn from:
+// a weight is negative or not a number, or they add up to zero.
+type InvalidWeightError struct {
+	StateIndex int
+	Input      Input
+}
+
+func (err InvalidWeightError) Error() string {
+	return fmt.Sprintf("outcome has invalid weights.  (State: %v, Input: %v)", err.StateIndex, err.Input)
+}
+
+// WeightedOutcome returns a CHOICE Outcome whose target is drawn at random among targets at spin time,
+// each with a probability proportional to its Weight.
+// Draws use the source set by SetRand, so they are reproducible from a seed.
+// Like any Outcome, an Action and a Guard can be set on the result.
+func WeightedOutcome(targets ...WeightedTarget) Outcome {
+	return Outcome{State: CHOICE, Weights: targets}
+}
+
+// SetRand sets the source of randomness for weighted Outcomes, e.g. rand.New(rand.NewSource(seed))
+// to make a simulation reproducible. A nil source restores the default one, seeded from the clock.
+// The source is only used while Spin holds the FSM lock, so it doesn't need to be shared with anything else.
+// This method is thread-safe.
+func (f *FSM) SetRand(r *rand.Rand) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.rand = r
+}
+
+// pickWeighted draws one of the targets of the weighted Outcome of state from for in.
+// Will return an InvalidWeightError if the weights can't be drawn from.
+// The caller must hold the lock.
+func (f *FSM) pickWeighted(from int, in Input, targets []WeightedTarget) (int, error) {
+	if f.rand == nil {
+		f.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	total, ok := totalWeight(targets)
+	if !ok {
+		return 0, InvalidWeightError{from, in}
+	}
+
+	x := f.rand.Float64() * total
+	for _, t := range targets {
+		if x < t.Weight {
+			return t.State, nil
+		}
+		x -= t.Weight
+	}
+	// Rounding can leave x just above the last weight.
+	for i := len(targets) - 1; i >= 0; i-- {
+		if targets[i].Weight > 0 {
+			return targets[i].State, nil
+		}
+	}
+	return 0, InvalidWeightError{from, in}
+}
+
+// targets returns the states the Outcome may move to, as far as is known without spinning:
+// every state listed in its Weights, or none if a Chooser picks it.
+// SAME_STATE doesn't lead anywhere new, so it has none either.
+func (o Outcome) targets() []int {
+	if o.State == SAME_STATE {
+		return nil
+	}
+	if o.State != CHOICE {
+		return []int{o.State}
+	}
+	targets := make([]int, 0, len(o.Weights))
+	if o.Chooser == nil {
+		for _, t := range o.Weights {
+			targets = append(targets, t.State)
+		}
+	}
+	return targets
+}
+
+// checkTarget returns an error if the Outcome of state from for in may lead to a state for which exists is false.
+// Targets picked by a Chooser aren't known, so they aren't checked.
+func checkTarget(from int, in Input, do Outcome, exists func(int) bool) error {
+	if do.State == SAME_STATE {
+		return nil
+	}
+	if do.State != CHOICE || (do.Chooser == nil && len(do.Weights) == 0) {
+		if !exists(do.State) {
+			return UndefinedTargetError{from, in, do.State}
+		}
+		return nil
+	}
+	if do.Chooser != nil {
+		return nil
+	}
+
+	for _, t := range do.Weights {
+		if !exists(t.State) {
+			return UndefinedTargetError{from, in, t.State}
+		}
+	}
+	if _, ok := totalWeight(do.Weights); !ok {
+		return InvalidWeightError{from, in}
+	}
+	return nil
+}
+
+// totalWeight returns the sum of the weights of targets,
+// and false if a weight is negative or not a number, or they don't add up to a positive finite number.
+func totalWeight(targets []WeightedTarget) (float64, bool) {
+	var total float64
+	for _, t := range targets {
+		if t.Weight < 0 || math.IsNaN(t.Weight) || math.IsInf(t.Weight, 1) {
+			return 0, false
+		}
+		total += t.Weight
+	}
+	if total <= 0 || math.IsInf(total, 1) {
+		return 0, false
+	}
+	return total, true
+}
+
+// sameWeights reports whether two weighted Outcomes list the same targets with the same weights, in the same order.
+func sameWeights(a, b []WeightedTarget) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
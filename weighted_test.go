@@ -0,0 +1,129 @@
+package fsm
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestWeightedOutcome(t *testing.T) {
+	ctx := context.Background()
+
+	state1 := State{
+		Index: test_state_1,
+		Outcomes: map[Input]Outcome{
+			test_input_1: WeightedOutcome(
+				WeightedTarget{State: test_state_2, Weight: 3},
+				WeightedTarget{State: test_state_3, Weight: 1},
+				WeightedTarget{State: 3, Weight: 0},
+			),
+		},
+	}
+	back := map[Input]Outcome{
+		test_input_2: Outcome{State: test_state_1, Action: NO_ACTION},
+	}
+	state2 := State{Index: test_state_2, Outcomes: back}
+	state3 := State{Index: test_state_3, Outcomes: back}
+	state4 := State{Index: 3, Outcomes: back}
+
+	fsm, err := Define(state1, state2, state3, state4)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	run := func(seed int64) []int {
+		fsm.SetRand(rand.New(rand.NewSource(seed)))
+		var visited []int
+		for i := 0; i < 1000; i++ {
+			if _, err := fsm.Spin(ctx, test_input_1); err != nil {
+				t.Fatal(err.Error())
+			}
+			visited = append(visited, fsm.Current())
+			if _, err := fsm.Spin(ctx, test_input_2); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+		return visited
+	}
+
+	first := run(42)
+	counts := map[int]int{}
+	for _, s := range first {
+		counts[s]++
+	}
+	if counts[3] != 0 {
+		t.Errorf("Target with zero weight picked %d times", counts[3])
+	}
+	if counts[test_state_2] < 650 || counts[test_state_2] > 850 {
+		t.Errorf("Wrong distribution. (Expected: about 750 of 1000, Got: %v)", counts[test_state_2])
+	}
+
+	second := run(42)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Same seed gave different draws at %d. (Expected: %v, Got: %v)", i, first[i], second[i])
+		}
+	}
+
+	// Weighted targets count for reachability, even with no weight.
+	if unreachable := fsm.UnreachableStates(); len(unreachable) != 0 {
+		t.Errorf("Wrong unreachable states. (Expected: [], Got: %v)", unreachable)
+	}
+}
+
+func TestWeightedOutcomeInvalid(t *testing.T) {
+	define := func(o Outcome) error {
+		_, err := Define(
+			State{Index: test_state_1, Outcomes: map[Input]Outcome{test_input_1: o}},
+			State{Index: test_state_2},
+		)
+		return err
+	}
+
+	err := define(WeightedOutcome(WeightedTarget{State: test_state_2, Weight: 1}, WeightedTarget{State: 7, Weight: 1}))
+	switch e := err.(type) {
+	case UndefinedTargetError:
+		if e.Target != 7 {
+			t.Errorf("Wrong undefined target. (Expected: 7, Got: %v)", e.Target)
+		}
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Errorf("Wrong error type: %T (%v)", err, err)
+	}
+
+	for _, o := range []Outcome{
+		WeightedOutcome(WeightedTarget{State: test_state_2, Weight: 0}),
+		WeightedOutcome(WeightedTarget{State: test_state_2, Weight: 2}, WeightedTarget{State: test_state_1, Weight: -1}),
+	} {
+		err := define(o)
+		switch err.(type) {
+		case InvalidWeightError:
+			t.Log("FSM corrently returned error: ", err)
+		default:
+			t.Errorf("Wrong error type: %T (%v)", err, err)
+		}
+	}
+
+	fsm, err := Define(State{Index: test_state_1}, State{Index: test_state_2})
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+	err = fsm.SetDefaultOutcome(WeightedOutcome(WeightedTarget{State: test_state_2, Weight: 0}))
+	switch err.(type) {
+	case InvalidWeightError:
+		t.Log("FSM corrently returned error: ", err)
+	default:
+		t.Errorf("Wrong error type: %T (%v)", err, err)
+	}
+
+	// Weights which slipped past validation are reported instead of drawn from.
+	for _, targets := range [][]WeightedTarget{nil, {{State: test_state_2, Weight: 0}}} {
+		_, err := fsm.pickWeighted(test_state_1, test_input_1, targets)
+		switch err.(type) {
+		case InvalidWeightError:
+			t.Log("FSM corrently returned error: ", err)
+		default:
+			t.Errorf("Wrong error type: %T (%v)", err, err)
+		}
+	}
+}
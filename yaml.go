@@ -0,0 +1,30 @@
+package fsm
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefineFromYAML defines an FSM from a YAML document with the same structure as DefineFromJSON accepts:
+//
+//	states:
+//	  - index: 0
+//	    outcomes:
+//	      - {input: 1, state: 1, action: open}
+//	  - index: 1
+//	    outcomes:
+//	      - {input: 1, state: 0}
+//	state_names: {0: closed, 1: opened}
+//	input_names: {1: toggle}
+//
+// Action names are resolved against actions before the registered ones, as by DefineFromJSONWithActions.
+// Will return an UnknownActionError if an outcome names an action which is neither in the map nor registered,
+// as well as any error Define would return.
+func DefineFromYAML(r io.Reader, actions map[string]Action) (*FSM, error) {
+	var d definition
+	if err := yaml.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	return d.define(actions)
+}
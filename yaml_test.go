@@ -0,0 +1,66 @@
+package fsm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testYAML = `
+states:
+  - index: 0
+    outcomes:
+      - {input: 0, state: 1, action: hit}
+      - {input: 1, state: 0}
+  - index: 1
+    outcomes:
+      - {input: 0, state: 0}
+state_names: {0: idle, 1: busy}
+input_names: {0: toggle}
+`
+
+func TestDefineFromYAML(t *testing.T) {
+	ctx := context.Background()
+
+	hit := false
+	actions := map[string]Action{
+		"hit": func(ctx context.Context) (context.Context, Input) { hit = true; return ctx, NO_INPUT },
+	}
+
+	fsm, err := DefineFromYAML(strings.NewReader(testYAML), actions)
+	if err != nil {
+		t.Fatal("Failed to define FSM: ", err)
+	}
+
+	if fsm.CurrentName() != "idle" {
+		t.Errorf("Wrong state name. (Expected: %v, Got: %v)", "idle", fsm.CurrentName())
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_2)
+	if !hit {
+		t.Errorf("Didn't hit named action.")
+	}
+	assertState(t, ctx, fsm, test_input_1, test_state_1)
+}
+
+func TestDefineFromYAMLErrors(t *testing.T) {
+	_, err := DefineFromYAML(strings.NewReader(testYAML), nil)
+	switch err.(type) {
+	case UnknownActionError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	_, err = DefineFromYAML(strings.NewReader("states: [{index: 0, outcomes: [{input: 0, state: 5}]}]"), nil)
+	switch err.(type) {
+	case UndefinedTargetError:
+		t.Logf("FSM corrently returned error: %v", err.Error())
+	default:
+		t.Fatalf("FSM returned wrong error type: %T", err)
+	}
+
+	_, err = DefineFromYAML(strings.NewReader("states: ["), nil)
+	if err == nil {
+		t.Fatalf("Didn't error on malformed YAML.")
+	}
+}